@@ -1,6 +1,6 @@
 module tideland.dev/go/dsa
 
-go 1.13
+go 1.21
 
 require (
 	tideland.dev/go/audit v0.4.0
@@ -0,0 +1,62 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Collect
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+)
+
+//--------------------
+// COLLECT
+//--------------------
+
+// Collect runs the MapReducer with the passed input instead of the one
+// returned by its Input() method and returns the reduced data instead
+// of passing it to Consume(). The job runs with the deterministic output
+// sorted by ID, the passed options may change it. So Collect() can be
+// used to test a MapReducer, e.g. against golden files.
+func Collect(mr MapReducer, input []Identifiable, options ...Option) ([]Identifiable, error) {
+	return CollectContext(context.Background(), mr, input, options...)
+}
+
+// CollectContext runs Collect() with a context.
+func CollectContext(ctx context.Context, mr MapReducer, input []Identifiable, options ...Option) ([]Identifiable, error) {
+	options = append([]Option{WithDeterministicOutput(nil)}, options...)
+	j := newMapReducerJob(ctx, mr, options)
+	j.input = func() <-chan Identifiable {
+		in := make(IdentifiableChan)
+		go func() {
+			defer in.Close()
+			for _, kv := range input {
+				select {
+				case <-j.ctx.Done():
+					return
+				case in <- kv:
+				}
+			}
+		}()
+		return in
+	}
+	var output []Identifiable
+	j.consume = func(in chan Identifiable) error {
+		for kv := range in {
+			output = append(output, kv)
+		}
+		return nil
+	}
+	if err := j.run(); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// EOF
@@ -0,0 +1,128 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestCollect tests collecting the output of a MapReducer.
+func TestCollect(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	input := []mapreduce.Identifiable{
+		&Word{"cherry", 1},
+		&Word{"apple", 1},
+		&Word{"banana", 2},
+		&Word{"avocado", 3},
+	}
+
+	output, err := mapreduce.Collect(NewInitialCounter(nil), input)
+	assert.Nil(err)
+	assert.Equal(output, []mapreduce.Identifiable{
+		&Word{"a", 4},
+		&Word{"b", 2},
+		&Word{"c", 1},
+	})
+}
+
+// TestCollectLess tests collecting with an own order.
+func TestCollectLess(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	input := []mapreduce.Identifiable{
+		&Word{"cherry", 1},
+		&Word{"apple", 1},
+		&Word{"banana", 2},
+		&Word{"avocado", 3},
+	}
+	byCount := func(a, b mapreduce.Identifiable) bool {
+		return a.(*Word).Count > b.(*Word).Count
+	}
+
+	output, err := mapreduce.Collect(NewInitialCounter(nil), input, mapreduce.WithDeterministicOutput(byCount))
+	assert.Nil(err)
+	assert.Equal(output, []mapreduce.Identifiable{
+		&Word{"a", 4},
+		&Word{"b", 2},
+		&Word{"c", 1},
+	})
+}
+
+// TestCollectRepeatable tests that multiple runs with multiple
+// workers return the same output.
+func TestCollectRepeatable(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	var input []mapreduce.Identifiable
+	var ids []string
+	for i := 0; i < 1000; i++ {
+		input = append(input, &Number{i})
+		ids = append(ids, strconv.Itoa(i))
+	}
+	sort.Strings(ids)
+
+	for i := 0; i < 5; i++ {
+		output, err := mapreduce.Collect(&NumberMapReducer{}, input,
+			mapreduce.WithMappers(8),
+			mapreduce.WithReducers(4),
+		)
+		assert.Nil(err)
+		assert.Length(output, len(ids))
+		for k, kv := range output {
+			assert.Equal(kv.ID(), ids[k])
+		}
+	}
+}
+
+// TestCollectError tests collecting with a failing MapReducer.
+func TestCollectError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	input := []mapreduce.Identifiable{
+		&Word{"apple", 1},
+	}
+
+	output, err := mapreduce.Collect(NewInitialCounter(errors.New("ouch")), input)
+	assert.ErrorMatch(err, "ouch")
+	assert.Nil(output)
+}
+
+// TestDeterministicOutput tests consuming the sorted output.
+func TestDeterministicOutput(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	last := -1
+	nmr := &NumberMapReducer{
+		count: 1000,
+		consume: func(n *Number) error {
+			assert.True(n.Value > last, "numbers are ordered")
+			last = n.Value
+			return nil
+		},
+	}
+	byValue := func(a, b mapreduce.Identifiable) bool {
+		return a.(*Number).Value < b.(*Number).Value
+	}
+
+	err := mapreduce.MapReduce(nmr, mapreduce.WithDeterministicOutput(byValue), mapreduce.WithReducers(4))
+	assert.Nil(err)
+	assert.Equal(nmr.consumed, 1000)
+	assert.Equal(last, 999)
+}
+
+// EOF
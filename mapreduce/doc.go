@@ -11,7 +11,35 @@
 // A type implementing the MapReducer interface has to be implemented
 // and passed to the MapReduce() function. The type is responsible
 // for the input, the mapping, the reducing and the consuming while
-// the package provides the runtime environment for it.
+// the package provides the runtime environment for it. Input and
+// output can also be provided by a Source like FromSlice() or FromDir()
+// and a Sink like ToSlice() or ToWriter(), passed via WithSource() and
+// WithSink().
+//
+// MapReduceContext() additionally stops a job when its context is
+// cancelled. Mapping and reducing may fail too if the MapReducer
+// implements the ContextMapper or ContextReducer interface. Panics
+// of the stages are recovered and returned as PanicError.
+//
+// Alternatively a GroupingMapReducer can be implemented. Here the
+// values are grouped by their IDs and passed to ReduceKey() together.
+// It is run by passing Grouped(gmr) to MapReduce().
+//
+// Multiple MapReducers can be chained with a Pipeline. Here the
+// reduced data of one stage is streamed into the next one.
+//
+// Never-ending input is processed with MapReduceStream(). It splits
+// the input into time or count based windows and consumes the results
+// of each window.
+//
+// MapReduceTyped() provides the same with type safe functions instead
+// of an interface. It needs no wrapper types and type assertions, the
+// engine running both works with type parameters.
+//
+// The order of the results passed to Consume() depends on the scheduling
+// of the goroutines. WithDeterministicOutput() sorts them, and Collect()
+// runs a MapReducer with in-memory input and returns the sorted output,
+// e.g. for tests.
 package mapreduce // import "tideland.dev/go/dsa/mapreduce"
 
 // EOF
@@ -0,0 +1,97 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Errors
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+//--------------------
+// PANIC ERROR
+//--------------------
+
+// Stages of a job where panics are recovered.
+const (
+	StageSource    = "source"
+	StagePartition = "partition"
+	StageMap       = "map"
+	StageCombine   = "combine"
+	StageReduce    = "reduce"
+	StageConsume   = "consume"
+)
+
+// PanicError is returned by a job when a stage panics. The ID is the one
+// of the processed value. In case of reducers and consumers reading from
+// channels it's the one of the last value handed over to them.
+type PanicError struct {
+	Stage string
+	ID    string
+	Value interface{}
+	Stack []byte
+}
+
+// Error implements the error interface.
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("panic during %s of ID %q: %v", pe.Stage, pe.ID, pe.Value)
+}
+
+// protect calls the function and converts a panic into a PanicError.
+func protect(stage string, id func() string, f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{
+				Stage: stage,
+				ID:    id(),
+				Value: r,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+	return f()
+}
+
+// staticID returns a function returning the given ID.
+func staticID(id string) func() string {
+	return func() string {
+		return id
+	}
+}
+
+// handover contains the last value handed over to a reducer or
+// the consumer. It's only written by the handing goroutine.
+type handover[T any] struct {
+	value T
+	ok    bool
+}
+
+// store sets the last value handed over.
+func (h *handover[T]) store(value T) {
+	h.value = value
+	h.ok = true
+}
+
+// handedID returns a function returning the ID of the last value
+// handed over. As it's written by another goroutine, the job is
+// cancelled and the end of the handing is awaited before reading it.
+func handedID[T any](cancel func(), done <-chan struct{}, h *handover[T], id func(T) string) func() string {
+	return func() string {
+		cancel()
+		<-done
+		if !h.ok {
+			return ""
+		}
+		return id(h.value)
+	}
+}
+
+// EOF
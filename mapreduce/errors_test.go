@@ -0,0 +1,132 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestMapPanic tests the recovering of a panicking mapper.
+func TestMapPanic(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	mr := &NumberMapReducer{
+		count: 10000,
+		mapf: func(n *Number) error {
+			if n.Value == 5000 {
+				panic("ouch")
+			}
+			return nil
+		},
+	}
+
+	err := mapreduce.MapReduce(mr)
+	var pe *mapreduce.PanicError
+	assert.True(errors.As(err, &pe))
+	assert.Equal(pe.Stage, mapreduce.StageMap)
+	assert.Equal(pe.ID, "5000")
+	assert.Equal(pe.Value, "ouch")
+	assert.True(len(pe.Stack) > 0, "stack is captured")
+	assert.ErrorMatch(err, `panic during map of ID "5000": ouch`)
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
+// TestReducePanic tests the recovering of a panicking reducer.
+func TestReducePanic(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	mr := &PanickingNumberMapReducer{&NumberMapReducer{count: 101}}
+
+	err := mapreduce.MapReduce(mr, mapreduce.WithMappers(1), mapreduce.WithReducers(1))
+	var pe *mapreduce.PanicError
+	assert.True(errors.As(err, &pe))
+	assert.Equal(pe.Stage, mapreduce.StageReduce)
+	assert.Equal(pe.ID, "100")
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
+// TestReduceKeyPanic tests the recovering of a panicking
+// grouping reducer.
+func TestReduceKeyPanic(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	wc := &PanickingWordCounter{NewWordCounter(1000, assert)}
+
+	err := mapreduce.MapReduce(mapreduce.Grouped(wc))
+	var pe *mapreduce.PanicError
+	assert.True(errors.As(err, &pe))
+	assert.Equal(pe.Stage, mapreduce.StageReduce)
+	assert.Equal(pe.ID, "delta")
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
+// TestConsumePanic tests the recovering of a panicking consumer.
+func TestConsumePanic(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	mr := &NumberMapReducer{
+		count: 10000,
+		consume: func(n *Number) error {
+			panic("ouch")
+		},
+	}
+
+	err := mapreduce.MapReduce(mr)
+	var pe *mapreduce.PanicError
+	assert.True(errors.As(err, &pe))
+	assert.Equal(pe.Stage, mapreduce.StageConsume)
+	assert.True(pe.ID != "", "ID of consumed value")
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// PanickingNumberMapReducer panics when reducing the number 100.
+type PanickingNumberMapReducer struct {
+	*NumberMapReducer
+}
+
+// Reduce passes the numbers through until 100.
+func (p *PanickingNumberMapReducer) Reduce(in, emit mapreduce.IdentifiableChan) {
+	for i := range in {
+		if i.(*Number).Value == 100 {
+			panic("ouch")
+		}
+		emit <- i
+	}
+}
+
+// PanickingWordCounter panics when reducing the word delta.
+type PanickingWordCounter struct {
+	*WordCounter
+}
+
+// ReduceKey counts the words until delta.
+func (p *PanickingWordCounter) ReduceKey(id string, values []mapreduce.Identifiable, emit mapreduce.IdentifiableChan) {
+	if id == "delta" {
+		panic("ouch")
+	}
+	p.WordCounter.ReduceKey(id, values, emit)
+}
+
+// EOF
@@ -0,0 +1,115 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Grouping
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"sort"
+)
+
+//--------------------
+// GROUPING MAP/REDUCE
+//--------------------
+
+// GroupingMapReducer is an alternative to the MapReducer. Here the
+// mapped values are grouped by their IDs and passed to the reducing
+// together.
+type GroupingMapReducer interface {
+	// Input has to return the input channel for the
+	// date to process.
+	Input() IdentifiableChan
+
+	// Map maps a key/value pair to another one and emits it.
+	Map(in Identifiable, emit IdentifiableChan)
+
+	// ReduceKey is called once per ID after the mapping with all
+	// values for this ID. It reduces them to the emit channel.
+	ReduceKey(id string, values []Identifiable, emit IdentifiableChan)
+
+	// Consume allows the GroupingMapReducer to consume the
+	// processed data.
+	Consume(in IdentifiableChan) error
+}
+
+// Grouped returns a MapReducer for the passed GroupingMapReducer, so
+// that it can be passed to MapReduce() or MapReduceContext(). Its
+// reducing groups all values by their IDs and calls ReduceKey()
+// for each of them in the order of the IDs.
+func Grouped(gmr GroupingMapReducer) MapReducer {
+	return &groupingMapReducer{
+		gmr: gmr,
+	}
+}
+
+// groupingMapReducer adapts a GroupingMapReducer to a MapReducer.
+type groupingMapReducer struct {
+	gmr GroupingMapReducer
+}
+
+// Input implements MapReducer.
+func (g *groupingMapReducer) Input() IdentifiableChan {
+	return g.gmr.Input()
+}
+
+// Map implements MapReducer.
+func (g *groupingMapReducer) Map(in Identifiable, emit IdentifiableChan) {
+	g.gmr.Map(in, emit)
+}
+
+// MapContext implements ContextMapper. It delegates to the
+// GroupingMapReducer if it implements ContextMapper too.
+func (g *groupingMapReducer) MapContext(ctx context.Context, in Identifiable, emit IdentifiableChan) error {
+	if cm, ok := g.gmr.(ContextMapper); ok {
+		return cm.MapContext(ctx, in, emit)
+	}
+	g.gmr.Map(in, emit)
+	return nil
+}
+
+// Reduce implements MapReducer.
+func (g *groupingMapReducer) Reduce(in, emit IdentifiableChan) {
+	_ = g.ReduceContext(context.Background(), in, emit)
+}
+
+// ReduceContext implements ContextReducer. It groups the values
+// and calls ReduceKey() per ID as long as the context is not done.
+func (g *groupingMapReducer) ReduceContext(ctx context.Context, in, emit IdentifiableChan) error {
+	groups := make(map[string][]Identifiable)
+	for kv := range in {
+		id := kv.ID()
+		groups[id] = append(groups[id], kv)
+	}
+	ids := make([]string, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := protect(StageReduce, staticID(id), func() error {
+			g.gmr.ReduceKey(id, groups[id], emit)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Consume implements MapReducer.
+func (g *groupingMapReducer) Consume(in IdentifiableChan) error {
+	return g.gmr.Consume(in)
+}
+
+// EOF
@@ -0,0 +1,182 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestGroupedMapReduce runs a word count where the values
+// are grouped by the framework.
+func TestGroupedMapReduce(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	wc := NewWordCounter(10000, assert)
+
+	err := mapreduce.MapReduce(mapreduce.Grouped(wc))
+	assert.Nil(err)
+	assert.Equal(wc.counts, wc.expected, "all words are counted")
+}
+
+// TestGroupedMapReduceOptions runs the grouped word count
+// with a single reducer.
+func TestGroupedMapReduceOptions(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	wc := NewWordCounter(10000, assert)
+
+	err := mapreduce.MapReduce(mapreduce.Grouped(wc), mapreduce.WithReducers(1))
+	assert.Nil(err)
+	assert.Equal(wc.counts, wc.expected, "all words are counted")
+}
+
+// TestGroupedMapReduceCombiner runs the grouped word count
+// with a combiner.
+func TestGroupedMapReduceCombiner(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	wc := &CombiningWordCounter{NewWordCounter(10000, assert)}
+
+	err := mapreduce.MapReduce(mapreduce.Grouped(wc), mapreduce.WithCombineBuffer(100))
+	assert.Nil(err)
+	assert.Equal(wc.counts, wc.expected, "all words are counted")
+	words := 0
+	for _, count := range wc.expected {
+		words += count
+	}
+	assert.Logf("reduced %d of %d values", wc.reduced, words)
+	assert.True(int(wc.reduced) < words/2, "less values have been reduced")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// vocabulary contains the words for the generated lines.
+var vocabulary = []string{
+	"alpha", "bravo", "charlie", "delta", "echo", "foxtrot",
+	"golf", "hotel", "india", "juliet", "kilo", "lima",
+}
+
+// Line is one line of text.
+type Line struct {
+	No   int
+	Text string
+}
+
+// ID returns an empty ID, lines are not grouped.
+func (l *Line) ID() string {
+	return ""
+}
+
+// Word contains a word and how often it has been found.
+type Word struct {
+	Word  string
+	Count int
+}
+
+// ID returns the word as identifier.
+func (w *Word) ID() string {
+	return w.Word
+}
+
+// WordCounter counts the words of generated lines.
+type WordCounter struct {
+	lines    int
+	expected map[string]int
+	counts   map[string]int
+	reduced  int64
+	assert   *asserts.Asserts
+}
+
+// NewWordCounter creates a word counter for the number of lines.
+func NewWordCounter(lines int, assert *asserts.Asserts) *WordCounter {
+	return &WordCounter{
+		lines:    lines,
+		expected: make(map[string]int),
+		counts:   make(map[string]int),
+		assert:   assert,
+	}
+}
+
+// Input generates the lines.
+func (wc *WordCounter) Input() mapreduce.IdentifiableChan {
+	input := make(mapreduce.IdentifiableChan)
+	lines := make([]*Line, wc.lines)
+	for i := 0; i < wc.lines; i++ {
+		words := make([]string, rand.Intn(10)+1)
+		for j := range words {
+			words[j] = vocabulary[rand.Intn(len(vocabulary))]
+			wc.expected[words[j]]++
+		}
+		lines[i] = &Line{i, strings.Join(words, " ")}
+	}
+	go func() {
+		defer close(input)
+		for _, line := range lines {
+			input <- line
+		}
+	}()
+	return input
+}
+
+// Map emits each word of a line.
+func (wc *WordCounter) Map(in mapreduce.Identifiable, emit mapreduce.IdentifiableChan) {
+	for _, word := range strings.Fields(in.(*Line).Text) {
+		emit <- &Word{word, 1}
+	}
+}
+
+// ReduceKey sums the counts of one word.
+func (wc *WordCounter) ReduceKey(id string, values []mapreduce.Identifiable, emit mapreduce.IdentifiableChan) {
+	count := 0
+	atomic.AddInt64(&wc.reduced, int64(len(values)))
+	for _, value := range values {
+		wc.assert.Equal(value.ID(), id, "value belongs to ID")
+		count += value.(*Word).Count
+	}
+	emit <- &Word{id, count}
+}
+
+// Consume collects the counted words.
+func (wc *WordCounter) Consume(in mapreduce.IdentifiableChan) error {
+	for i := range in {
+		word := i.(*Word)
+		_, ok := wc.counts[word.Word]
+		wc.assert.False(ok, "each word only once")
+		wc.counts[word.Word] = word.Count
+	}
+	return nil
+}
+
+// CombiningWordCounter combines the counted words per mapper.
+type CombiningWordCounter struct {
+	*WordCounter
+}
+
+// Combine sums the counts of one word.
+func (cwc *CombiningWordCounter) Combine(id string, values []mapreduce.Identifiable, emit mapreduce.IdentifiableChan) {
+	count := 0
+	for _, value := range values {
+		count += value.(*Word).Count
+	}
+	emit <- &Word{id, count}
+}
+
+// EOF
@@ -12,8 +12,13 @@ package mapreduce // import "tideland.dev/go/dsa/mapreduce"
 //--------------------
 
 import (
-	"hash/adler32"
-	"runtime"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"tideland.dev/go/trace/failure"
 )
 
 //--------------------
@@ -57,99 +62,545 @@ type MapReducer interface {
 	Consume(in IdentifiableChan) error
 }
 
+// ContextMapper can be implemented by a MapReducer additionally. In
+// this case MapContext() is called instead of Map(). It receives the
+// context of the job and stops the whole job when returning an error.
+type ContextMapper interface {
+	// MapContext maps a key/value pair to another one and emits it.
+	MapContext(ctx context.Context, in Identifiable, emit IdentifiableChan) error
+}
+
+// ContextReducer can be implemented by a MapReducer additionally. In
+// this case ReduceContext() is called instead of Reduce(). It receives
+// the context of the job and stops the whole job when returning an error.
+type ContextReducer interface {
+	// ReduceContext reduces the values delivered via the input
+	// channel to the emit channel.
+	ReduceContext(ctx context.Context, in, emit IdentifiableChan) error
+}
+
+// Combiner can be implemented by a MapReducer or a GroupingMapReducer
+// additionally. In this case each mapper collects the emitted values
+// per ID and lets Combine() pre-aggregate them before they are passed
+// to the reducers.
+type Combiner interface {
+	// Combine combines the values of one ID and emits the result.
+	Combine(id string, values []Identifiable, emit IdentifiableChan)
+}
+
 // MapReduce applies a map and a reduce function to keys and values in parallel.
-func MapReduce(mr MapReducer) error {
-	mapEmitChan := make(IdentifiableChan)
-	reduceEmitChan := make(IdentifiableChan)
+// The options allow to configure the job, e.g. the number of mappers and reducers.
+func MapReduce(mr MapReducer, options ...Option) error {
+	return MapReduceContext(context.Background(), mr, options...)
+}
+
+// MapReduceContext applies a map and a reduce function to keys and values
+// in parallel like MapReduce. Additionally the job stops when the context
+// is cancelled or any stage returns an error. All mapping and reducing
+// goroutines are terminated before the first error is returned. Data still
+// sent to the input channel is drained in the background until the producer
+// closes it.
+func MapReduceContext(ctx context.Context, mr MapReducer, options ...Option) error {
+	return newMapReducerJob(ctx, mr, options).run()
+}
 
-	go performReducing(mr, mapEmitChan, reduceEmitChan)
-	go performMapping(mr, mapEmitChan)
+// identifiableJob is the job running a MapReducer.
+type identifiableJob = job[Identifiable, Identifiable, Identifiable]
 
-	return mr.Consume(reduceEmitChan)
+// newMapReducerJob creates a job for the given MapReducer. Its
+// methods, the source, and the sink are adapted to the functions
+// of the job.
+func newMapReducerJob(ctx context.Context, mr MapReducer, opts []Option) *identifiableJob {
+	j := newJob[Identifiable, Identifiable, Identifiable](ctx, opts)
+	j.inputID = Identifiable.ID
+	j.mappedID = Identifiable.ID
+	j.outputID = Identifiable.ID
+	j.input = func() <-chan Identifiable {
+		return mr.Input()
+	}
+	if source := j.opts.source; source != nil {
+		j.input = func() <-chan Identifiable {
+			j.wg.Add(1)
+			return runSource(j.ctx, source, func(err error) {
+				defer j.wg.Done()
+				if err != nil {
+					j.fail(err)
+				}
+			})
+		}
+	}
+	j.consume = func(in chan Identifiable) error {
+		return mr.Consume(in)
+	}
+	if sink := j.opts.sink; sink != nil {
+		j.consume = func(in chan Identifiable) error {
+			return sink(in)
+		}
+	}
+	if cm, ok := mr.(ContextMapper); ok {
+		j.mapf = func(ctx context.Context, in Identifiable, emit chan Identifiable) error {
+			return cm.MapContext(ctx, in, emit)
+		}
+	} else {
+		j.mapf = func(ctx context.Context, in Identifiable, emit chan Identifiable) error {
+			mr.Map(in, emit)
+			return nil
+		}
+	}
+	if cr, ok := mr.(ContextReducer); ok {
+		j.reducef = func(ctx context.Context, in, emit chan Identifiable) error {
+			return cr.ReduceContext(ctx, in, emit)
+		}
+	} else {
+		j.reducef = func(ctx context.Context, in, emit chan Identifiable) error {
+			mr.Reduce(in, emit)
+			return nil
+		}
+	}
+	if c := combinerOf(mr); c != nil {
+		j.combine = func(id string, values []Identifiable, emit chan Identifiable) {
+			c.Combine(id, values, emit)
+		}
+	}
+	if codec := j.opts.codec; codec != nil {
+		j.encode = codec.Encode
+		j.decode = codec.Decode
+	}
+	if j.opts.ordered {
+		j.less = j.opts.less
+	}
+	return j
 }
 
 //--------------------
-// PRIVATE
+// JOB
 //--------------------
 
-// closerChan signals the closing of channels.
-type closerChan chan struct{}
-
-// closerChan closes given channel after a number of signals.
-func newCloserChan(kvc IdentifiableChan, size int) closerChan {
-	signals := make(closerChan)
-	go func() {
-		ctr := 0
-		for {
-			<-signals
-			ctr++
-			if ctr == size {
-				kvc.Close()
-				close(signals)
-				return
-			}
-		}
-	}()
-	return signals
+// job contains the runtime environment of one map/reduce. It reads
+// the input values, maps them to values passed to the reducers by
+// their IDs, and lets the consumer read the output values. The
+// APIs are adapted to it by setting its functions.
+type job[In, M, Out any] struct {
+	parent   context.Context
+	ctx      context.Context
+	cancel   func()
+	opts     *options
+	input    func() <-chan In
+	consume  func(in chan Out) error
+	mapf     func(ctx context.Context, in In, emit chan M) error
+	reducef  func(ctx context.Context, in chan M, emit chan Out) error
+	combine  func(id string, values []M, emit chan M)
+	inputID  func(in In) string
+	mappedID func(m M) string
+	outputID func(out Out) string
+	encode   func(m M) ([]byte, error)
+	decode   func(data []byte) (M, error)
+	less     func(a, b Out) bool
+	stats    *stats
+	shared   bool
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	err      error
 }
 
-// performReducing runs the reducing goroutines.
-func performReducing(mr MapReducer, mapEmitChan, reduceEmitChan IdentifiableChan) {
-	// Start a closer for the reduce emit chan.
-	size := runtime.NumCPU()
-	signals := newCloserChan(reduceEmitChan, size)
+// newJob creates a job with the options. The functions
+// have to be set by the creator.
+func newJob[In, M, Out any](ctx context.Context, opts []Option) *job[In, M, Out] {
+	j := &job[In, M, Out]{
+		parent: ctx,
+		opts:   newOptions(opts),
+	}
+	j.stats = newStats(j.opts.reducers)
+	j.ctx, j.cancel = context.WithCancel(ctx)
+	return j
+}
 
-	// Start reduce goroutines.
-	reduceChans := make([]IdentifiableChan, size)
-	for i := 0; i < size; i++ {
-		reduceChans[i] = make(IdentifiableChan)
-		go func(in IdentifiableChan) {
-			mr.Reduce(in, reduceEmitChan)
-			signals <- struct{}{}
-		}(reduceChans[i])
+// run starts mapping and reducing, lets the consumer read
+// the results, and waits until all goroutines are done.
+func (j *job[In, M, Out]) run() error {
+	defer j.cancel()
+
+	mapEmitChan := make(chan M, j.opts.mapEmitBuffer)
+	reduceEmitChan := make(chan Out, j.opts.reduceEmitBuffer)
+	consumeChan := make(chan Out, j.opts.reduceEmitBuffer)
+
+	progressDone := make(chan struct{})
+	progressStopped := make(chan struct{})
+	if j.opts.progress != nil {
+		go func() {
+			defer close(progressStopped)
+			j.stats.reportProgress(j.opts.progressInterval, j.opts.progress, progressDone)
+		}()
+	} else {
+		close(progressStopped)
 	}
 
-	// Read map emitted data.
-	for kv := range mapEmitChan {
-		hash := adler32.Checksum([]byte(kv.ID()))
-		idx := hash % uint32(size)
-		reduceChans[idx] <- kv
+	var consumed handover[Out]
+	forwarded := make(chan struct{})
+
+	j.wg.Add(3)
+	go j.performReducing(mapEmitChan, reduceEmitChan)
+	go j.performMapping(mapEmitChan)
+	go j.performForwarding(reduceEmitChan, consumeChan, &consumed, forwarded)
+
+	if err := protect(StageConsume, handedID(j.cancel, forwarded, &consumed, j.outputID), func() error {
+		return j.consume(consumeChan)
+	}); err != nil {
+		j.fail(err)
 	}
+	// Consume may have returned early, so stop the job
+	// and wait for the termination of all goroutines.
+	j.cancel()
+	drain(consumeChan)
+	j.wg.Wait()
 
-	// Close reduce channels.
-	for _, reduceChan := range reduceChans {
-		reduceChan.Close()
+	// Finish the statistics.
+	j.finish(&j.stats.end)
+	close(progressDone)
+	<-progressStopped
+	if j.opts.progress != nil {
+		j.opts.progress(j.stats.snapshot())
 	}
+	if j.opts.stats != nil {
+		*j.opts.stats = j.stats.snapshot()
+	}
+
+	return j.result()
 }
 
-// Perform the mapping.
-func performMapping(mr MapReducer, mapEmitChan IdentifiableChan) {
-	// Start a closer for the map emit chan.
-	size := runtime.NumCPU() * 4
-	signals := newCloserChan(mapEmitChan, size)
+// fail stores the first error of the job and cancels it. Errors
+// of stages returning due to the cancellation are ignored.
+func (j *job[In, M, Out]) fail(err error) {
+	if ctxErr := j.ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+		return
+	}
+	j.mu.Lock()
+	if j.err == nil {
+		j.err = err
+	}
+	j.mu.Unlock()
+	j.cancel()
+}
+
+// result returns the first error of the job or the one
+// of the parent context.
+func (j *job[In, M, Out]) result() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.err != nil {
+		return j.err
+	}
+	return j.parent.Err()
+}
+
+// finish stores the end time of a phase. Shared statistics
+// are only counted, their phases are finished by the owner.
+func (j *job[In, M, Out]) finish(end *atomic.Int64) {
+	if !j.shared {
+		j.stats.finish(end)
+	}
+}
+
+// performMapping starts the mapping goroutines and dispatches
+// the input data to them.
+func (j *job[In, M, Out]) performMapping(mapEmitChan chan M) {
+	defer j.wg.Done()
 
 	// Start map goroutines.
-	mapChans := make([]IdentifiableChan, size)
+	size := j.opts.mappers
+	var mwg sync.WaitGroup
+	mapChans := make([]chan In, size)
 	for i := 0; i < size; i++ {
-		mapChans[i] = make(IdentifiableChan)
-		go func(in IdentifiableChan) {
+		mapChans[i] = make(chan In, j.opts.mapBuffer)
+		mwg.Add(1)
+		go func(in chan In) {
+			defer mwg.Done()
+			emit := mapEmitChan
+			if j.combine != nil {
+				// Map into a local channel for combining.
+				emit = make(chan M, j.opts.mapEmitBuffer)
+				combined := make(chan struct{})
+				go func() {
+					defer close(combined)
+					j.performCombining(emit, mapEmitChan)
+				}()
+				defer func() {
+					close(emit)
+					<-combined
+				}()
+			}
 			for kv := range in {
-				mr.Map(kv, mapEmitChan)
+				if j.ctx.Err() != nil {
+					continue
+				}
+				if err := protect(StageMap, func() string {
+					return j.inputID(kv)
+				}, func() error {
+					return j.mapf(j.ctx, kv, emit)
+				}); err != nil {
+					j.fail(err)
+				}
+				j.stats.mapped.Add(1)
 			}
-			signals <- struct{}{}
 		}(mapChans[i])
 	}
 
 	// Dispatch input data to map channels.
+	j.dispatch(j.input(), mapChans)
+
+	// Close map channels and signal the end of mapping.
+	for _, mapChan := range mapChans {
+		close(mapChan)
+	}
+	mwg.Wait()
+	close(mapEmitChan)
+	j.finish(&j.stats.mapEnd)
+}
+
+// performCombining collects the values emitted by one mapper per ID.
+// Each time the combine buffer is full and at the end of the mapping
+// they are passed to the combiner sorted by ID.
+func (j *job[In, M, Out]) performCombining(in, out chan M) {
+	groups := make(map[string][]M)
+	count := 0
+	flush := func() {
+		ids := make([]string, 0, len(groups))
+		for id := range groups {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			values := groups[id]
+			if err := protect(StageCombine, staticID(id), func() error {
+				j.combine(id, values, out)
+				return nil
+			}); err != nil {
+				j.fail(err)
+				break
+			}
+		}
+		groups = make(map[string][]M)
+		count = 0
+	}
+	for kv := range in {
+		if j.ctx.Err() != nil {
+			continue
+		}
+		id := j.mappedID(kv)
+		groups[id] = append(groups[id], kv)
+		count++
+		if count >= j.opts.combineBuffer {
+			flush()
+		}
+	}
+	if j.ctx.Err() == nil {
+		flush()
+	}
+}
+
+// dispatch distributes the input data round-robin to the map
+// channels until the input is closed or the job is cancelled.
+func (j *job[In, M, Out]) dispatch(input <-chan In, mapChans []chan In) {
 	idx := 0
-	for kv := range mr.Input() {
-		mapChans[idx%size] <- kv
-		idx++
+	for {
+		select {
+		case <-j.ctx.Done():
+			go drain(input)
+			return
+		case kv, ok := <-input:
+			if !ok {
+				return
+			}
+			j.stats.read.Add(1)
+			select {
+			case <-j.ctx.Done():
+				go drain(input)
+				return
+			case mapChans[idx%len(mapChans)] <- kv:
+				idx++
+			}
+		}
 	}
+}
 
-	// Close map channels.
+// performReducing starts the reducing goroutines and routes the
+// map emitted data to them.
+func (j *job[In, M, Out]) performReducing(mapEmitChan chan M, reduceEmitChan chan Out) {
+	defer j.wg.Done()
+
+	// Start reduce goroutines.
+	size := j.opts.reducers
+	var rwg sync.WaitGroup
+	reduceChans := make([]chan M, size)
+	handed := make([]handover[M], size)
+	shuffled := make(chan struct{})
 	for i := 0; i < size; i++ {
-		mapChans[i].Close()
+		reduceChans[i] = make(chan M, j.opts.reduceBuffer)
+		rwg.Add(1)
+		go func(idx int, in chan M) {
+			defer rwg.Done()
+			if err := protect(StageReduce, handedID(j.cancel, shuffled, &handed[idx], j.mappedID), func() error {
+				return j.reducef(j.ctx, in, reduceEmitChan)
+			}); err != nil {
+				j.fail(err)
+			}
+			// Reduce may have returned early.
+			drain(in)
+		}(i, reduceChans[i])
+	}
+
+	// Shuffle map emitted data in memory or on disk.
+	if j.encode != nil {
+		j.spill(mapEmitChan, reduceChans, handed)
+	} else {
+		j.route(mapEmitChan, reduceChans, handed)
+	}
+	close(shuffled)
+
+	// Close reduce channels and signal the end of reducing.
+	for _, reduceChan := range reduceChans {
+		close(reduceChan)
+	}
+	rwg.Wait()
+	close(reduceEmitChan)
+	j.finish(&j.stats.reduceEnd)
+}
+
+// route passes the map emitted data directly to the reduce channels
+// and remembers the last value handed over to each of them. After a
+// cancellation it still has to be read until the mappers are done.
+func (j *job[In, M, Out]) route(mapEmitChan chan M, reduceChans []chan M, handed []handover[M]) {
+	for kv := range mapEmitChan {
+		if j.ctx.Err() != nil {
+			continue
+		}
+		j.stats.emitted.Add(1)
+		id := j.mappedID(kv)
+		idx, ok := j.partition(id, len(reduceChans))
+		if !ok {
+			continue
+		}
+		handed[idx].store(kv)
+		select {
+		case <-j.ctx.Done():
+		case reduceChans[idx] <- kv:
+			j.stats.reduced(idx)
+		}
+	}
+}
+
+// partition returns the index of the reducer for the ID. An
+// invalid index returned by the partitioner lets the job fail.
+func (j *job[In, M, Out]) partition(id string, size int) (int, bool) {
+	var idx int
+	if err := protect(StagePartition, staticID(id), func() error {
+		idx = j.opts.partitioner.Partition(id, size)
+		return nil
+	}); err != nil {
+		j.fail(err)
+		return 0, false
+	}
+	if idx < 0 || idx >= size {
+		j.fail(failure.New("invalid partition %d for ID %q", idx, id))
+		return 0, false
+	}
+	return idx, true
+}
+
+// performForwarding passes the reduced data to the consumer. In case
+// of a cancellation the consume channel is closed at once while the
+// reduced data is drained until the reducers are done. Data reduced
+// after the cancellation is never passed on. The last value handed
+// over to the consumer is remembered until the forwarding is done.
+func (j *job[In, M, Out]) performForwarding(reduceEmitChan, consumeChan chan Out, consumed *handover[Out], forwarded chan struct{}) {
+	defer j.wg.Done()
+	defer drain(reduceEmitChan)
+	defer close(consumeChan)
+	defer close(forwarded)
+
+	if j.less != nil {
+		j.forwardOrdered(reduceEmitChan, consumeChan, consumed)
+		return
+	}
+	for {
+		select {
+		case <-j.ctx.Done():
+			return
+		case kv, ok := <-reduceEmitChan:
+			if !ok || j.ctx.Err() != nil {
+				return
+			}
+			consumed.store(kv)
+			select {
+			case <-j.ctx.Done():
+				return
+			case consumeChan <- kv:
+				j.stats.consumed.Add(1)
+			}
+		}
+	}
+}
+
+// forwardOrdered collects all reduced data and passes it
+// sorted to the consumer.
+func (j *job[In, M, Out]) forwardOrdered(reduceEmitChan, consumeChan chan Out, consumed *handover[Out]) {
+	var results []Out
+	for collecting := true; collecting; {
+		select {
+		case <-j.ctx.Done():
+			return
+		case kv, ok := <-reduceEmitChan:
+			if !ok {
+				collecting = false
+				continue
+			}
+			results = append(results, kv)
+		}
+	}
+	if err := protect(StageConsume, staticID(""), func() error {
+		sort.SliceStable(results, func(i, k int) bool {
+			return j.less(results[i], results[k])
+		})
+		return nil
+	}); err != nil {
+		j.fail(err)
+		return
+	}
+	for _, kv := range results {
+		if j.ctx.Err() != nil {
+			return
+		}
+		consumed.store(kv)
+		select {
+		case <-j.ctx.Done():
+			return
+		case consumeChan <- kv:
+			j.stats.consumed.Add(1)
+		}
+	}
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// combinerOf returns the Combiner of a MapReducer or of
+// the GroupingMapReducer it adapts. Otherwise nil.
+func combinerOf(mr MapReducer) Combiner {
+	if c, ok := mr.(Combiner); ok {
+		return c
+	}
+	if g, ok := mr.(*groupingMapReducer); ok {
+		if c, ok := g.gmr.(Combiner); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// drain reads a channel until it is closed.
+func drain[T any](c <-chan T) {
+	for range c {
 	}
 }
 
@@ -12,10 +12,14 @@ package mapreduce_test
 //--------------------
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"runtime"
 	"strconv"
 	"testing"
+	"time"
 
 	"tideland.dev/go/audit/asserts"
 	"tideland.dev/go/dsa/identifier"
@@ -56,6 +60,117 @@ func TestMapReduce(t *testing.T) {
 	}
 }
 
+// TestMapReduceOptions runs the order scenario with
+// different worker and buffer configurations.
+func TestMapReduceOptions(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	tests := []struct {
+		name    string
+		options []mapreduce.Option
+	}{
+		{"single workers", []mapreduce.Option{
+			mapreduce.WithMappers(1),
+			mapreduce.WithReducers(1),
+		}},
+		{"many mappers", []mapreduce.Option{
+			mapreduce.WithMappers(250),
+			mapreduce.WithReducers(2),
+		}},
+		{"buffered", []mapreduce.Option{
+			mapreduce.WithMapBuffer(10),
+			mapreduce.WithMapEmitBuffer(100),
+			mapreduce.WithReduceBuffer(100),
+			mapreduce.WithReduceEmitBuffer(100),
+		}},
+		{"invalid values", []mapreduce.Option{
+			mapreduce.WithMappers(0),
+			mapreduce.WithReducers(-1),
+			mapreduce.WithMapBuffer(-1),
+		}},
+	}
+	for _, test := range tests {
+		assert.Logf("test: %s", test.name)
+		mr := &OrderMapReducer{10000, make(map[int][]*OrderItem), make(map[string]*OrderItemAnalysis), assert}
+		err := mapreduce.MapReduce(mr, test.options...)
+		assert.Nil(err)
+		assert.Equal(len(mr.items), len(mr.analyses), "all items are analyzed")
+	}
+}
+
+// TestMapReduceCombiner runs the order scenario with
+// a combiner.
+func TestMapReduceCombiner(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	mr := &CombiningOrderMapReducer{&OrderMapReducer{10000, make(map[int][]*OrderItem), make(map[string]*OrderItemAnalysis), assert}}
+	err := mapreduce.MapReduce(mr)
+	assert.Nil(err)
+	assert.Equal(len(mr.items), len(mr.analyses), "all items are analyzed")
+	for _, analysis := range mr.analyses {
+		quantity := 0
+		for _, item := range mr.items[analysis.ArticleNo] {
+			quantity += item.Count
+		}
+		assert.Equal(quantity, analysis.Quantity, "quantity per article")
+	}
+}
+
+// TestMapReduceContextCancel tests the stopping of a job by
+// cancelling its context.
+func TestMapReduceContextCancel(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	mr := &NumberMapReducer{
+		count: 100000,
+		consume: func(n *Number) error {
+			if n.Value == 1000 {
+				cancel()
+			}
+			return nil
+		},
+	}
+	err := mapreduce.MapReduceContext(ctx, mr)
+	assert.True(errors.Is(err, context.Canceled))
+	assert.True(mr.consumed < mr.count, "stopped before the end")
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
+// TestMapReduceContextMapError tests the stopping of a job by
+// an error returned by the mapper.
+func TestMapReduceContextMapError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	mr := &NumberMapReducer{
+		count: 100000,
+		mapf: func(n *Number) error {
+			if n.Value == 5000 {
+				return errors.New("cannot map 5000")
+			}
+			return nil
+		},
+	}
+	err := mapreduce.MapReduceContext(context.Background(), mr)
+	assert.ErrorMatch(err, "cannot map 5000")
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
+// TestMapReduceConsumeError tests the stopping of a job by
+// an error returned by the consumer.
+func TestMapReduceConsumeError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	mr := &NumberMapReducer{
+		count: 100000,
+		consume: func(n *Number) error {
+			return errors.New("cannot consume")
+		},
+	}
+	err := mapreduce.MapReduce(mr)
+	assert.ErrorMatch(err, "cannot consume")
+	assert.Equal(mr.consumed, 1)
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
 // BenchmarkMapReduce tests the performance of map/reduce.
 func BenchmarkMapReduce(b *testing.B) {
 	assert := asserts.NewPanic()
@@ -67,6 +182,86 @@ func BenchmarkMapReduce(b *testing.B) {
 // HELPERS
 //--------------------
 
+// waitGoroutines waits until the number of goroutines is back
+// to the given number.
+func waitGoroutines(n int) bool {
+	for i := 0; i < 100; i++ {
+		if runtime.NumGoroutine() <= n {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+// Number is a simple identifiable value.
+type Number struct {
+	Value int
+}
+
+// ID returns the value as identifier.
+func (n *Number) ID() string {
+	return strconv.Itoa(n.Value)
+}
+
+// NumberMapReducer passes numbers through the map/reduce and
+// allows to hook into mapping and consuming.
+type NumberMapReducer struct {
+	count    int
+	consumed int
+	mapf     func(n *Number) error
+	consume  func(n *Number) error
+}
+
+// Input generates the numbers. The input generator stops
+// when the job drains the channel.
+func (n *NumberMapReducer) Input() mapreduce.IdentifiableChan {
+	input := make(mapreduce.IdentifiableChan)
+	go func() {
+		defer close(input)
+		for i := 0; i < n.count; i++ {
+			input <- &Number{i}
+		}
+	}()
+	return input
+}
+
+// Map is not used, see MapContext.
+func (n *NumberMapReducer) Map(in mapreduce.Identifiable, emit mapreduce.IdentifiableChan) {
+	panic("not used")
+}
+
+// MapContext emits the number after calling the hook.
+func (n *NumberMapReducer) MapContext(ctx context.Context, in mapreduce.Identifiable, emit mapreduce.IdentifiableChan) error {
+	if n.mapf != nil {
+		if err := n.mapf(in.(*Number)); err != nil {
+			return err
+		}
+	}
+	emit <- in
+	return nil
+}
+
+// Reduce passes the numbers through.
+func (n *NumberMapReducer) Reduce(in, emit mapreduce.IdentifiableChan) {
+	for i := range in {
+		emit <- i
+	}
+}
+
+// Consume counts the numbers after calling the hook.
+func (n *NumberMapReducer) Consume(in mapreduce.IdentifiableChan) error {
+	for i := range in {
+		n.consumed++
+		if n.consume != nil {
+			if err := n.consume(i.(*Number)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 type OrderMapReducer struct {
 	count    int
 	items    map[int][]*OrderItem
@@ -153,6 +348,24 @@ func (o *OrderMapReducer) Consume(in mapreduce.IdentifiableChan) error {
 	return nil
 }
 
+// CombiningOrderMapReducer combines the analyses per mapper.
+type CombiningOrderMapReducer struct {
+	*OrderMapReducer
+}
+
+// Combine sums the analyses of one article.
+func (c *CombiningOrderMapReducer) Combine(id string, values []mapreduce.Identifiable, emit mapreduce.IdentifiableChan) {
+	combined := &OrderItemAnalysis{}
+	for _, value := range values {
+		analysis := value.(*OrderItemAnalysis)
+		combined.ArticleNo = analysis.ArticleNo
+		combined.Quantity += analysis.Quantity
+		combined.Amount += analysis.Amount
+		combined.Discount += analysis.Discount
+	}
+	emit <- combined
+}
+
 // Order item type.
 type OrderItem struct {
 	ArticleNo    int
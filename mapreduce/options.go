@@ -0,0 +1,213 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Options
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"runtime"
+	"time"
+)
+
+//--------------------
+// OPTIONS
+//--------------------
+
+// options contains the configuration of a map/reduce job.
+type options struct {
+	mappers          int
+	reducers         int
+	mapBuffer        int
+	mapEmitBuffer    int
+	reduceBuffer     int
+	reduceEmitBuffer int
+	partitioner      Partitioner
+	combineBuffer    int
+	codec            Codec
+	spillDir         string
+	spillLimit       int
+	stats            *Stats
+	progressInterval time.Duration
+	progress         func(stats Stats)
+	ordered          bool
+	less             func(a, b Identifiable) bool
+	source           Source
+	sink             Sink
+}
+
+// newOptions returns the default options modified by
+// the passed ones.
+func newOptions(opts []Option) *options {
+	o := &options{
+		mappers:       runtime.NumCPU() * 4,
+		reducers:      runtime.NumCPU(),
+		partitioner:   NewHashPartitioner(nil),
+		combineBuffer: 1024,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Option defines a function setting an option of a map/reduce job.
+type Option func(o *options)
+
+// WithMappers sets the number of mapping goroutines. Default is
+// four times the number of CPUs.
+func WithMappers(n int) Option {
+	return func(o *options) {
+		o.mappers = atLeast(n, 1)
+	}
+}
+
+// WithReducers sets the number of reducing goroutines. Default is
+// the number of CPUs.
+func WithReducers(n int) Option {
+	return func(o *options) {
+		o.reducers = atLeast(n, 1)
+	}
+}
+
+// WithMapBuffer sets the buffer size of the channels dispatching
+// the input data to each mapper. Default is unbuffered.
+func WithMapBuffer(n int) Option {
+	return func(o *options) {
+		o.mapBuffer = atLeast(n, 0)
+	}
+}
+
+// WithMapEmitBuffer sets the buffer size of the channel the mappers
+// are emitting to. Default is unbuffered.
+func WithMapEmitBuffer(n int) Option {
+	return func(o *options) {
+		o.mapEmitBuffer = atLeast(n, 0)
+	}
+}
+
+// WithReduceBuffer sets the buffer size of the channels routing
+// the mapped data to each reducer. Default is unbuffered.
+func WithReduceBuffer(n int) Option {
+	return func(o *options) {
+		o.reduceBuffer = atLeast(n, 0)
+	}
+}
+
+// WithReduceEmitBuffer sets the buffer size of the channels the
+// reducers are emitting to and the consumer is reading from. Default
+// is unbuffered.
+func WithReduceEmitBuffer(n int) Option {
+	return func(o *options) {
+		o.reduceEmitBuffer = atLeast(n, 0)
+	}
+}
+
+// WithPartitioner sets the partitioner deciding which reducer processes
+// the mapped data. Default is the hash partitioner using Adler-32.
+func WithPartitioner(p Partitioner) Option {
+	return func(o *options) {
+		if p != nil {
+			o.partitioner = p
+		}
+	}
+}
+
+// WithCombineBuffer sets the number of values each mapper collects
+// before passing them to the Combiner. Default is 1024.
+func WithCombineBuffer(n int) Option {
+	return func(o *options) {
+		o.combineBuffer = atLeast(n, 1)
+	}
+}
+
+// WithSpill lets the job shuffle the mapped data on disk instead of
+// in memory. It is serialized with the codec and written as sorted
+// runs into a temporary directory inside of dir, each time the limit
+// of buffered values is reached. In case of an empty dir the default
+// temporary directory is used. After the mapping the runs are merged
+// per reducer, so that each one receives its values sorted by ID.
+func WithSpill(codec Codec, dir string, limit int) Option {
+	return func(o *options) {
+		o.codec = codec
+		o.spillDir = dir
+		o.spillLimit = atLeast(limit, 1)
+	}
+}
+
+// WithStats lets the job store its statistics at the end in
+// the passed variable.
+func WithStats(stats *Stats) Option {
+	return func(o *options) {
+		o.stats = stats
+	}
+}
+
+// WithProgress lets the job call the passed function with the current
+// statistics in the given interval and a last time at the end.
+func WithProgress(interval time.Duration, progress func(stats Stats)) Option {
+	return func(o *options) {
+		if interval <= 0 {
+			interval = time.Second
+		}
+		o.progressInterval = interval
+		o.progress = progress
+	}
+}
+
+// WithSource lets the job read its input from the source instead
+// of the Input() method of the MapReducer.
+func WithSource(source Source) Option {
+	return func(o *options) {
+		o.source = source
+	}
+}
+
+// WithSink lets the job pass its results to the sink instead of the
+// Consume() method of the MapReducer. When streaming it is used instead
+// of a WindowConsumer too.
+func WithSink(sink Sink) Option {
+	return func(o *options) {
+		o.sink = sink
+	}
+}
+
+// WithDeterministicOutput lets the job run with one mapper and one
+// reducer and pass the results to the consumer sorted by the less
+// function. In case of nil they are sorted by their IDs. As the results
+// have to be collected before they are consumed this mode is intended
+// for tests and small data sets. Later options may change the number of
+// mappers and reducers again, the output stays sorted.
+func WithDeterministicOutput(less func(a, b Identifiable) bool) Option {
+	return func(o *options) {
+		if less == nil {
+			less = func(a, b Identifiable) bool {
+				return a.ID() < b.ID()
+			}
+		}
+		o.mappers = 1
+		o.reducers = 1
+		o.ordered = true
+		o.less = less
+	}
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// atLeast returns n if it is not lower than lower, otherwise lower.
+func atLeast(n, lower int) int {
+	if n < lower {
+		return lower
+	}
+	return n
+}
+
+// EOF
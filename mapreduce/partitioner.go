@@ -0,0 +1,196 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Partitioner
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"hash/adler32"
+	"sort"
+	"strconv"
+	"sync"
+
+	"tideland.dev/go/trace/failure"
+)
+
+//--------------------
+// PARTITIONER
+//--------------------
+
+// Partitioner decides which reducer processes the data
+// with a given ID.
+type Partitioner interface {
+	// Partition returns the index of the reducer for the ID. It
+	// has to be between 0 and size-1.
+	Partition(id string, size int) int
+}
+
+// PartitionerFunc allows to use a simple function as Partitioner.
+type PartitionerFunc func(id string, size int) int
+
+// Partition implements the Partitioner interface.
+func (pf PartitionerFunc) Partition(id string, size int) int {
+	return pf(id, size)
+}
+
+// NewHashPartitioner returns a partitioner using the modulo of the passed
+// hash function, e.g. crc32.ChecksumIEEE. In case of nil Adler-32 is used,
+// which is the default partitioner of a job.
+func NewHashPartitioner(hash func(data []byte) uint32) Partitioner {
+	if hash == nil {
+		hash = adler32.Checksum
+	}
+	return PartitionerFunc(func(id string, size int) int {
+		return int(hash([]byte(id)) % uint32(size))
+	})
+}
+
+// rangePartitioner assigns IDs to reducers based on sorted bounds.
+type rangePartitioner struct {
+	bounds []string
+}
+
+// NewRangePartitioner returns a partitioner distributing the IDs based on
+// their order. IDs lower than the first bound are passed to the first reducer,
+// IDs lower than the second bound to the second one, and so on. IDs beyond
+// the last bound or the number of reducers are passed to the last reducer.
+func NewRangePartitioner(bounds ...string) Partitioner {
+	rp := &rangePartitioner{
+		bounds: append([]string{}, bounds...),
+	}
+	sort.Strings(rp.bounds)
+	return rp
+}
+
+// Partition implements the Partitioner interface.
+func (rp *rangePartitioner) Partition(id string, size int) int {
+	idx := sort.Search(len(rp.bounds), func(i int) bool {
+		return rp.bounds[i] > id
+	})
+	if idx >= size {
+		return size - 1
+	}
+	return idx
+}
+
+// consistentHashPartitioner assigns IDs to reducers based on a hash ring.
+type consistentHashPartitioner struct {
+	replicas int
+	mu       sync.Mutex
+	rings    map[int]*hashRing
+}
+
+// NewConsistentHashPartitioner returns a partitioner placing each reducer
+// multiple times on a hash ring. The IDs are passed to the reducer following
+// next on the ring. So changing the number of reducers moves only few IDs
+// and the number of replicas smoothes the distribution.
+func NewConsistentHashPartitioner(replicas int) Partitioner {
+	return &consistentHashPartitioner{
+		replicas: atLeast(replicas, 1),
+		rings:    make(map[int]*hashRing),
+	}
+}
+
+// Partition implements the Partitioner interface.
+func (chp *consistentHashPartitioner) Partition(id string, size int) int {
+	chp.mu.Lock()
+	ring, ok := chp.rings[size]
+	if !ok {
+		ring = newHashRing(size, chp.replicas)
+		chp.rings[size] = ring
+	}
+	chp.mu.Unlock()
+	return ring.lookup(id)
+}
+
+// PartitionSkew distributes the sample IDs with the partitioner to the given
+// number of reducers. It returns the load of each reducer and the skew as ratio
+// between the highest and the average load. So a skew of 1.0 means a perfect
+// distribution while a skew equal to size means that one reducer gets all IDs.
+// Like in a job an invalid index returned by the partitioner is an error.
+func PartitionSkew(p Partitioner, size int, ids []string) ([]int, float64, error) {
+	if size < 1 {
+		return nil, 0.0, failure.New("invalid number of partitions %d", size)
+	}
+	loads := make([]int, size)
+	for _, id := range ids {
+		idx := p.Partition(id, size)
+		if idx < 0 || idx >= size {
+			return nil, 0.0, failure.New("invalid partition %d for ID %q", idx, id)
+		}
+		loads[idx]++
+	}
+	highest := 0
+	for _, load := range loads {
+		if load > highest {
+			highest = load
+		}
+	}
+	if len(ids) == 0 {
+		return loads, 0.0, nil
+	}
+	avg := float64(len(ids)) / float64(size)
+	return loads, float64(highest) / avg, nil
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// hashRing contains the sorted hashes of the reducer replicas.
+type hashRing struct {
+	hashes  []uint32
+	indices map[uint32]int
+}
+
+// newHashRing creates a ring for the number of reducers.
+func newHashRing(size, replicas int) *hashRing {
+	hr := &hashRing{
+		indices: make(map[uint32]int),
+	}
+	for i := 0; i < size; i++ {
+		for r := 0; r < replicas; r++ {
+			hash := fnv1a(strconv.Itoa(i) + "#" + strconv.Itoa(r))
+			if _, ok := hr.indices[hash]; ok {
+				continue
+			}
+			hr.indices[hash] = i
+			hr.hashes = append(hr.hashes, hash)
+		}
+	}
+	sort.Slice(hr.hashes, func(i, j int) bool {
+		return hr.hashes[i] < hr.hashes[j]
+	})
+	return hr
+}
+
+// lookup returns the reducer index for the ID.
+func (hr *hashRing) lookup(id string) int {
+	hash := fnv1a(id)
+	idx := sort.Search(len(hr.hashes), func(i int) bool {
+		return hr.hashes[i] >= hash
+	})
+	if idx == len(hr.hashes) {
+		idx = 0
+	}
+	return hr.indices[hr.hashes[idx]]
+}
+
+// fnv1a calculates the 32 bit FNV-1a hash of a string.
+func fnv1a(s string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint32(s[i])
+		hash *= 16777619
+	}
+	return hash
+}
+
+// EOF
@@ -0,0 +1,165 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"hash/crc32"
+	"strconv"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestHashPartitioner tests the hash partitioner with the
+// default and a passed hash function.
+func TestHashPartitioner(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	ids := generateIDs(10000)
+
+	for _, p := range []mapreduce.Partitioner{
+		mapreduce.NewHashPartitioner(nil),
+		mapreduce.NewHashPartitioner(crc32.ChecksumIEEE),
+	} {
+		for _, id := range ids {
+			idx := p.Partition(id, 7)
+			assert.True(idx >= 0 && idx < 7, "index in range")
+			assert.Equal(p.Partition(id, 7), idx, "index is stable")
+		}
+	}
+
+	_, adlerSkew, err := mapreduce.PartitionSkew(mapreduce.NewHashPartitioner(nil), 16, ids)
+	assert.Nil(err)
+	_, crcSkew, err := mapreduce.PartitionSkew(mapreduce.NewHashPartitioner(crc32.ChecksumIEEE), 16, ids)
+	assert.Nil(err)
+	assert.Logf("skew of short IDs: Adler-32 %.2f / CRC-32 %.2f", adlerSkew, crcSkew)
+	assert.True(crcSkew < adlerSkew, "CRC-32 distributes short IDs better")
+}
+
+// TestRangePartitioner tests the distribution based on ranges.
+func TestRangePartitioner(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	p := mapreduce.NewRangePartitioner("n", "g", "t")
+
+	assert.Equal(p.Partition("a", 4), 0)
+	assert.Equal(p.Partition("g", 4), 1)
+	assert.Equal(p.Partition("m", 4), 1)
+	assert.Equal(p.Partition("n", 4), 2)
+	assert.Equal(p.Partition("s", 4), 2)
+	assert.Equal(p.Partition("z", 4), 3)
+	// Less reducers than ranges.
+	assert.Equal(p.Partition("a", 2), 0)
+	assert.Equal(p.Partition("s", 2), 1)
+	assert.Equal(p.Partition("z", 2), 1)
+}
+
+// TestConsistentHashPartitioner tests the distribution based
+// on the hash ring.
+func TestConsistentHashPartitioner(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	p := mapreduce.NewConsistentHashPartitioner(100)
+	ids := generateIDs(10000)
+
+	loads, skew, err := mapreduce.PartitionSkew(p, 8, ids)
+	assert.Nil(err)
+	assert.Length(loads, 8)
+	assert.Logf("skew of consistent hashing: %.2f", skew)
+	assert.True(skew < 2.0, "acceptable skew")
+
+	// Adding a reducer only moves a part of the IDs.
+	moved := 0
+	for _, id := range ids {
+		if p.Partition(id, 8) != p.Partition(id, 9) {
+			moved++
+		}
+	}
+	assert.True(moved < len(ids)/4, "only few IDs moved")
+}
+
+// TestPartitionSkew tests the calculation of the skew.
+func TestPartitionSkew(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	first := mapreduce.PartitionerFunc(func(id string, size int) int {
+		return 0
+	})
+	modulo := mapreduce.PartitionerFunc(func(id string, size int) int {
+		n, _ := strconv.Atoi(id)
+		return n % size
+	})
+	invalid := mapreduce.PartitionerFunc(func(id string, size int) int {
+		return size
+	})
+	ids := generateIDs(100)
+
+	loads, skew, err := mapreduce.PartitionSkew(first, 4, ids)
+	assert.Nil(err)
+	assert.Equal(loads, []int{100, 0, 0, 0})
+	assert.Equal(skew, 4.0)
+	loads, skew, err = mapreduce.PartitionSkew(modulo, 4, ids)
+	assert.Nil(err)
+	assert.Equal(loads, []int{25, 25, 25, 25})
+	assert.Equal(skew, 1.0)
+	_, skew, err = mapreduce.PartitionSkew(modulo, 4, nil)
+	assert.Nil(err)
+	assert.Equal(skew, 0.0)
+	_, _, err = mapreduce.PartitionSkew(invalid, 4, ids)
+	assert.ErrorMatch(err, `.*invalid partition 4 for ID "0".*`)
+	_, _, err = mapreduce.PartitionSkew(modulo, 0, ids)
+	assert.ErrorMatch(err, ".*invalid number of partitions 0.*")
+}
+
+// TestMapReducePartitioners runs the order scenario with
+// the different partitioners.
+func TestMapReducePartitioners(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	for _, p := range []mapreduce.Partitioner{
+		mapreduce.NewHashPartitioner(crc32.ChecksumIEEE),
+		mapreduce.NewRangePartitioner("2", "4", "6", "8"),
+		mapreduce.NewConsistentHashPartitioner(50),
+	} {
+		mr := &OrderMapReducer{10000, make(map[int][]*OrderItem), make(map[string]*OrderItemAnalysis), assert}
+		err := mapreduce.MapReduce(mr, mapreduce.WithPartitioner(p))
+		assert.Nil(err)
+		assert.Equal(len(mr.items), len(mr.analyses), "all items are analyzed")
+	}
+}
+
+// TestMapReduceInvalidPartition tests the handling of a
+// partitioner returning invalid indices.
+func TestMapReduceInvalidPartition(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	p := mapreduce.PartitionerFunc(func(id string, size int) int {
+		return size
+	})
+	mr := &NumberMapReducer{count: 1000}
+	err := mapreduce.MapReduce(mr, mapreduce.WithPartitioner(p))
+	assert.ErrorMatch(err, ".*invalid partition.*")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// generateIDs creates short numeric IDs.
+func generateIDs(count int) []string {
+	ids := make([]string, count)
+	for i := 0; i < count; i++ {
+		ids[i] = strconv.Itoa(i)
+	}
+	return ids
+}
+
+// EOF
@@ -0,0 +1,140 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Pipeline
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"tideland.dev/go/trace/failure"
+)
+
+//--------------------
+// STAGE ERROR
+//--------------------
+
+// StageError is returned by a pipeline when one of its stages fails.
+// The index of the first stage is 0.
+type StageError struct {
+	Index int
+	Err   error
+}
+
+// Error implements the error interface.
+func (se *StageError) Error() string {
+	return fmt.Sprintf("pipeline stage %d failed: %v", se.Index, se.Err)
+}
+
+// Unwrap returns the error of the stage.
+func (se *StageError) Unwrap() error {
+	return se.Err
+}
+
+//--------------------
+// PIPELINE
+//--------------------
+
+// pipelineStage contains one MapReducer of a pipeline
+// with its options.
+type pipelineStage struct {
+	mr      MapReducer
+	options []Option
+}
+
+// Pipeline chains multiple MapReducers. The input of the first stage is
+// read from its Input(), the reduced data of each stage is streamed into
+// the mapping of the next one, and the last stage consumes the results.
+// So Consume() of the earlier stages and Input() of the later ones are
+// not used. All stages run concurrently.
+type Pipeline struct {
+	stages []pipelineStage
+}
+
+// NewPipeline creates an empty pipeline.
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Stage appends a MapReducer with its options to the pipeline.
+func (p *Pipeline) Stage(mr MapReducer, options ...Option) *Pipeline {
+	p.stages = append(p.stages, pipelineStage{mr, options})
+	return p
+}
+
+// Run runs all stages of the pipeline until the last one consumed
+// all results, the context is cancelled, or a stage fails. In the
+// latter case the first error is returned as StageError. A failing
+// stage cancels the later ones before their input ends, so they
+// don't process partial data as complete.
+func (p *Pipeline) Run(ctx context.Context) error {
+	if len(p.stages) == 0 {
+		return failure.New("pipeline has no stages")
+	}
+	pctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	var input func() <-chan Identifiable
+	for i, stage := range p.stages {
+		j := newMapReducerJob(pctx, stage.mr, stage.options)
+		if input != nil {
+			j.input = input
+		}
+		var link IdentifiableChan
+		if i < len(p.stages)-1 {
+			link = make(IdentifiableChan)
+			j.consume = func(in chan Identifiable) error {
+				for kv := range in {
+					select {
+					case <-pctx.Done():
+						return pctx.Err()
+					case link <- kv:
+					}
+				}
+				return nil
+			}
+			input = func() <-chan Identifiable {
+				return link
+			}
+		}
+		wg.Add(1)
+		go func(idx int, link IdentifiableChan) {
+			defer wg.Done()
+			err := j.run()
+			if err != nil {
+				if pctx.Err() == nil || !errors.Is(err, pctx.Err()) {
+					once.Do(func() {
+						firstErr = &StageError{idx, err}
+					})
+				}
+				// Cancel the later stages before closing the link, so
+				// they don't take the partial input as complete.
+				cancel()
+			}
+			if link != nil {
+				link.Close()
+			}
+		}(i, link)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// EOF
@@ -0,0 +1,222 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestPipeline runs a word count followed by a count of
+// the words per initial letter.
+func TestPipeline(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	wc := NewWordCounter(10000, assert)
+	ic := NewInitialCounter(nil)
+
+	err := mapreduce.NewPipeline().
+		Stage(mapreduce.Grouped(wc)).
+		Stage(ic, mapreduce.WithReducers(2)).
+		Run(context.Background())
+	assert.Nil(err)
+	expected := make(map[string]int)
+	for word, count := range wc.expected {
+		expected[word[:1]] += count
+	}
+	assert.Equal(ic.counts, expected, "all initials are counted")
+	assert.Empty(wc.counts, "consume of first stage is not used")
+}
+
+// TestPipelineError tests the reporting of a failing stage.
+func TestPipelineError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	wc := NewWordCounter(10000, assert)
+	ic := NewInitialCounter(errors.New("ouch"))
+
+	err := mapreduce.NewPipeline().
+		Stage(mapreduce.Grouped(wc)).
+		Stage(ic).
+		Stage(NewInitialCounter(nil)).
+		Run(context.Background())
+	var se *mapreduce.StageError
+	assert.True(errors.As(err, &se))
+	assert.Equal(se.Index, 1)
+	assert.ErrorMatch(se.Err, "ouch")
+	assert.ErrorMatch(err, "pipeline stage 1 failed: ouch")
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
+// TestPipelineErrorPartialInput tests that the later stages don't
+// process the partial input of a failing stage as complete.
+func TestPipelineErrorPartialInput(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	// The source delays the end of the failing stage, so that the
+	// later ones would finish their partial input before.
+	source := func(ctx context.Context, emit mapreduce.IdentifiableChan) error {
+		for i := 0; i < 2000; i++ {
+			select {
+			case <-ctx.Done():
+				time.Sleep(20 * time.Millisecond)
+				return ctx.Err()
+			case emit <- &Number{i}:
+			}
+		}
+		return nil
+	}
+	for i := 0; i < 20; i++ {
+		numbers := &NumberMapReducer{
+			mapf: func(n *Number) error {
+				if n.Value == 1000 {
+					return errors.New("ouch")
+				}
+				return nil
+			},
+		}
+		summer := &NumberSummer{}
+
+		err := mapreduce.NewPipeline().
+			Stage(numbers, mapreduce.WithSource(source)).
+			Stage(&NumberMapReducer{}).
+			Stage(summer).
+			Run(context.Background())
+		var se *mapreduce.StageError
+		assert.True(errors.As(err, &se))
+		assert.Equal(se.Index, 0)
+		assert.Empty(summer.sums, "no sums of partial input")
+	}
+}
+
+// TestPipelineCancel tests the cancellation of a pipeline.
+func TestPipelineCancel(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := mapreduce.NewPipeline().
+		Stage(&NumberMapReducer{count: 10000}).
+		Stage(&NumberMapReducer{}).
+		Run(ctx)
+	assert.True(errors.Is(err, context.Canceled))
+}
+
+// TestPipelineEmpty tests running an empty pipeline.
+func TestPipelineEmpty(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	err := mapreduce.NewPipeline().Run(context.Background())
+	assert.ErrorMatch(err, ".*pipeline has no stages.*")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// InitialCounter counts the words per initial letter.
+type InitialCounter struct {
+	err    error
+	counts map[string]int
+}
+
+// NewInitialCounter creates an initial counter. If err is not
+// nil it is returned by the mapping.
+func NewInitialCounter(err error) *InitialCounter {
+	return &InitialCounter{
+		err:    err,
+		counts: make(map[string]int),
+	}
+}
+
+// Input is not used.
+func (ic *InitialCounter) Input() mapreduce.IdentifiableChan {
+	panic("not used")
+}
+
+// Map is not used, see MapContext.
+func (ic *InitialCounter) Map(in mapreduce.Identifiable, emit mapreduce.IdentifiableChan) {
+	panic("not used")
+}
+
+// MapContext emits the count of a word with its initial.
+func (ic *InitialCounter) MapContext(ctx context.Context, in mapreduce.Identifiable, emit mapreduce.IdentifiableChan) error {
+	if ic.err != nil {
+		return ic.err
+	}
+	word := in.(*Word)
+	emit <- &Word{word.Word[:1], word.Count}
+	return nil
+}
+
+// Reduce sums the counts per initial.
+func (ic *InitialCounter) Reduce(in, emit mapreduce.IdentifiableChan) {
+	counts := make(map[string]int)
+	for i := range in {
+		counts[i.ID()] += i.(*Word).Count
+	}
+	for initial, count := range counts {
+		emit <- &Word{initial, count}
+	}
+}
+
+// Consume collects the counts.
+func (ic *InitialCounter) Consume(in mapreduce.IdentifiableChan) error {
+	for i := range in {
+		word := i.(*Word)
+		ic.counts[word.Word] = word.Count
+	}
+	return nil
+}
+
+// NumberSummer sums the numbers per reducer.
+type NumberSummer struct {
+	sums []int
+}
+
+// Input is not used.
+func (ns *NumberSummer) Input() mapreduce.IdentifiableChan {
+	panic("not used")
+}
+
+// Map passes the numbers through.
+func (ns *NumberSummer) Map(in mapreduce.Identifiable, emit mapreduce.IdentifiableChan) {
+	emit <- in
+}
+
+// Reduce emits the sum of all numbers at the end of the input.
+func (ns *NumberSummer) Reduce(in, emit mapreduce.IdentifiableChan) {
+	sum := 0
+	for i := range in {
+		sum += i.(*Number).Value
+	}
+	emit <- &Number{sum}
+}
+
+// Consume collects the sums.
+func (ns *NumberSummer) Consume(in mapreduce.IdentifiableChan) error {
+	for i := range in {
+		ns.sums = append(ns.sums, i.(*Number).Value)
+	}
+	return nil
+}
+
+// EOF
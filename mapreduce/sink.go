@@ -0,0 +1,69 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Sinks
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"io"
+
+	"tideland.dev/go/trace/failure"
+)
+
+//--------------------
+// SINK
+//--------------------
+
+// Sink consumes the results of a job instead of the Consume() method
+// of the MapReducer. Returning an error stops the job.
+type Sink func(in IdentifiableChan) error
+
+// ToSlice appends the results to the slice.
+func ToSlice(dst *[]Identifiable) Sink {
+	return func(in IdentifiableChan) error {
+		for kv := range in {
+			*dst = append(*dst, kv)
+		}
+		return nil
+	}
+}
+
+// ToMap stores the results in the map using their IDs as keys. So
+// in case of equal IDs only the last result is kept.
+func ToMap(dst map[string]Identifiable) Sink {
+	return func(in IdentifiableChan) error {
+		for kv := range in {
+			dst[kv.ID()] = kv
+		}
+		return nil
+	}
+}
+
+// ToWriter writes each result as one line to the writer. The line is
+// created by the format function. In case of nil it contains the ID
+// and the result formatted with %v separated by a tab.
+func ToWriter(w io.Writer, format func(kv Identifiable) string) Sink {
+	if format == nil {
+		format = func(kv Identifiable) string {
+			return fmt.Sprintf("%s\t%v", kv.ID(), kv)
+		}
+	}
+	return func(in IdentifiableChan) error {
+		for kv := range in {
+			if _, err := fmt.Fprintln(w, format(kv)); err != nil {
+				return failure.Annotate(err, "cannot write ID %q", kv.ID())
+			}
+		}
+		return nil
+	}
+}
+
+// EOF
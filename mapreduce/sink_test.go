@@ -0,0 +1,108 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestToSlice tests collecting the results in a slice.
+func TestToSlice(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	nmr := &NumberMapReducer{count: 1000}
+	var output []mapreduce.Identifiable
+
+	err := mapreduce.MapReduce(nmr, mapreduce.WithSink(mapreduce.ToSlice(&output)))
+	assert.Nil(err)
+	assert.Length(output, 1000)
+	assert.Equal(nmr.consumed, 0, "consume is not used")
+}
+
+// TestToMap tests collecting the results in a map.
+func TestToMap(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	wc := NewWordCounter(1000, assert)
+	output := make(map[string]mapreduce.Identifiable)
+
+	err := mapreduce.MapReduce(mapreduce.Grouped(wc), mapreduce.WithSink(mapreduce.ToMap(output)))
+	assert.Nil(err)
+	assert.Length(output, len(wc.expected))
+	for word, count := range wc.expected {
+		assert.Equal(output[word].(*Word).Count, count)
+	}
+}
+
+// TestToWriter tests writing the results line by line.
+func TestToWriter(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	words := []mapreduce.Identifiable{
+		&Word{"b", 2},
+		&Word{"a", 1},
+	}
+	var buf bytes.Buffer
+
+	err := mapreduce.MapReduce(PassThrough{},
+		mapreduce.WithSource(mapreduce.FromSlice(words)),
+		mapreduce.WithSink(mapreduce.ToWriter(&buf, nil)),
+		mapreduce.WithDeterministicOutput(nil),
+	)
+	assert.Nil(err)
+	assert.Equal(buf.String(), "a\t&{a 1}\nb\t&{b 2}\n")
+
+	buf.Reset()
+	format := func(kv mapreduce.Identifiable) string {
+		return strings.ToUpper(kv.ID())
+	}
+	err = mapreduce.MapReduce(PassThrough{},
+		mapreduce.WithSource(mapreduce.FromSlice(words)),
+		mapreduce.WithSink(mapreduce.ToWriter(&buf, format)),
+		mapreduce.WithDeterministicOutput(nil),
+	)
+	assert.Nil(err)
+	assert.Equal(buf.String(), "A\nB\n")
+}
+
+// TestToWriterError tests stopping a job by a failing writer.
+func TestToWriterError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	nmr := &NumberMapReducer{count: 1000}
+
+	err := mapreduce.MapReduce(nmr, mapreduce.WithSink(mapreduce.ToWriter(FailingWriter{}, nil)))
+	assert.ErrorMatch(err, ".*cannot write ID.*ouch.*")
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// FailingWriter returns an error on each write.
+type FailingWriter struct{}
+
+// Write implements io.Writer.
+func (fw FailingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("ouch")
+}
+
+// EOF
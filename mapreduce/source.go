@@ -0,0 +1,195 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Sources
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"tideland.dev/go/trace/failure"
+)
+
+//--------------------
+// SOURCE
+//--------------------
+
+// Source produces the input data of a job instead of the Input() method
+// of the MapReducer. It runs in an own goroutine and passes the values
+// to emit until it is done or the context is cancelled. The channel is
+// closed by the job when the source returns. Returning an error stops
+// the job.
+type Source func(ctx context.Context, emit IdentifiableChan) error
+
+// Line is a line of text emitted by the reader based sources. The
+// number starts with 1. Name is the one of the read file, if any.
+type Line struct {
+	Name string
+	No   int
+	Text string
+}
+
+// ID returns the name, if any, and the number of the line.
+func (l *Line) ID() string {
+	if l.Name == "" {
+		return strconv.Itoa(l.No)
+	}
+	return l.Name + ":" + strconv.Itoa(l.No)
+}
+
+// KeyValue is a pair emitted by FromMap().
+type KeyValue[V any] struct {
+	Key   string
+	Value V
+}
+
+// ID returns the key.
+func (kv *KeyValue[V]) ID() string {
+	return kv.Key
+}
+
+// FromSlice emits the values of the slice in their order.
+func FromSlice(values []Identifiable) Source {
+	return func(ctx context.Context, emit IdentifiableChan) error {
+		for _, value := range values {
+			if err := send(ctx, emit, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// FromMap emits the entries of the map as KeyValue sorted by key.
+func FromMap[V any](m map[string]V) Source {
+	return func(ctx context.Context, emit IdentifiableChan) error {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := send(ctx, emit, &KeyValue[V]{key, m[key]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// FromReader emits the lines read from the reader as Line.
+func FromReader(r io.Reader) Source {
+	return func(ctx context.Context, emit IdentifiableChan) error {
+		return scan(ctx, emit, "", bufio.NewScanner(r))
+	}
+}
+
+// FromScanner emits the tokens of the scanner as Line. So the
+// split function and the buffer of the scanner can be configured.
+func FromScanner(s *bufio.Scanner) Source {
+	return func(ctx context.Context, emit IdentifiableChan) error {
+		return scan(ctx, emit, "", s)
+	}
+}
+
+// FromDir emits the lines of the regular files in the directory as Line
+// named by the path of the file. The files are read in the order of their
+// names. If the pattern is not empty only the files whose names match it
+// are read, see filepath.Match() for its syntax.
+func FromDir(dir, pattern string) Source {
+	return func(ctx context.Context, emit IdentifiableChan) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return failure.Annotate(err, "cannot read directory %q", dir)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			if pattern != "" {
+				matched, err := filepath.Match(pattern, entry.Name())
+				if err != nil {
+					return failure.Annotate(err, "invalid pattern %q", pattern)
+				}
+				if !matched {
+					continue
+				}
+			}
+			if err := scanFile(ctx, emit, filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// runSource starts the source in a goroutine and returns the channel
+// it emits to. The done function is called with its result before
+// the channel is closed.
+func runSource(ctx context.Context, source Source, done func(err error)) IdentifiableChan {
+	input := make(IdentifiableChan)
+	go func() {
+		defer input.Close()
+		done(protect(StageSource, staticID(""), func() error {
+			return source(ctx, input)
+		}))
+	}()
+	return input
+}
+
+// send passes the value to emit unless the context is cancelled.
+func send(ctx context.Context, emit IdentifiableChan, value Identifiable) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case emit <- value:
+		return nil
+	}
+}
+
+// scan emits the tokens of the scanner as lines with the given name.
+func scan(ctx context.Context, emit IdentifiableChan, name string, s *bufio.Scanner) error {
+	no := 0
+	for s.Scan() {
+		no++
+		if err := send(ctx, emit, &Line{name, no, s.Text()}); err != nil {
+			return err
+		}
+	}
+	if err := s.Err(); err != nil {
+		if name == "" {
+			return failure.Annotate(err, "cannot scan input")
+		}
+		return failure.Annotate(err, "cannot scan file %q", name)
+	}
+	return nil
+}
+
+// scanFile emits the lines of the file.
+func scanFile(ctx context.Context, emit IdentifiableChan, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return failure.Annotate(err, "cannot open file %q", name)
+	}
+	defer f.Close()
+	return scan(ctx, emit, name, bufio.NewScanner(f))
+}
+
+// EOF
@@ -0,0 +1,229 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestFromSlice tests reading the input from a slice.
+func TestFromSlice(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	ic := NewInitialCounter(nil)
+	words := []mapreduce.Identifiable{
+		&Word{"apple", 1},
+		&Word{"banana", 2},
+		&Word{"avocado", 3},
+	}
+
+	err := mapreduce.MapReduce(ic, mapreduce.WithSource(mapreduce.FromSlice(words)))
+	assert.Nil(err)
+	assert.Equal(ic.counts, map[string]int{"a": 4, "b": 2})
+}
+
+// TestFromMap tests reading the input from a map.
+func TestFromMap(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	var output []mapreduce.Identifiable
+
+	err := mapreduce.MapReduce(PassThrough{},
+		mapreduce.WithSource(mapreduce.FromMap(map[string]int{"b": 2, "a": 1})),
+		mapreduce.WithSink(mapreduce.ToSlice(&output)),
+		mapreduce.WithDeterministicOutput(nil),
+	)
+	assert.Nil(err)
+	assert.Equal(output, []mapreduce.Identifiable{
+		&mapreduce.KeyValue[int]{"a", 1},
+		&mapreduce.KeyValue[int]{"b", 2},
+	})
+}
+
+// TestFromReader tests reading the input lines from a reader.
+func TestFromReader(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	var output []mapreduce.Identifiable
+
+	err := mapreduce.MapReduce(PassThrough{},
+		mapreduce.WithSource(mapreduce.FromReader(strings.NewReader("one\ntwo\nthree\n"))),
+		mapreduce.WithSink(mapreduce.ToSlice(&output)),
+		mapreduce.WithDeterministicOutput(nil),
+	)
+	assert.Nil(err)
+	assert.Equal(output, []mapreduce.Identifiable{
+		&mapreduce.Line{"", 1, "one"},
+		&mapreduce.Line{"", 2, "two"},
+		&mapreduce.Line{"", 3, "three"},
+	})
+}
+
+// TestFromScanner tests reading the input tokens from a scanner.
+func TestFromScanner(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	scanner := bufio.NewScanner(strings.NewReader("one two\nthree"))
+	scanner.Split(bufio.ScanWords)
+	var output []mapreduce.Identifiable
+
+	err := mapreduce.MapReduce(PassThrough{},
+		mapreduce.WithSource(mapreduce.FromScanner(scanner)),
+		mapreduce.WithSink(mapreduce.ToSlice(&output)),
+		mapreduce.WithDeterministicOutput(nil),
+	)
+	assert.Nil(err)
+	assert.Length(output, 3)
+	assert.Equal(output[2].(*mapreduce.Line).Text, "three")
+}
+
+// TestFromDir tests reading the input lines from the files
+// of a directory.
+func TestFromDir(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.txt": "alpha\nbeta\n",
+		"b.txt": "gamma\n",
+		"c.log": "delta\n",
+	}
+	for name, content := range files {
+		assert.Nil(os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	assert.Nil(os.Mkdir(filepath.Join(dir, "d.txt"), 0755))
+	output := make(map[string]mapreduce.Identifiable)
+
+	err := mapreduce.MapReduce(PassThrough{},
+		mapreduce.WithSource(mapreduce.FromDir(dir, "*.txt")),
+		mapreduce.WithSink(mapreduce.ToMap(output)),
+	)
+	assert.Nil(err)
+	assert.Length(output, 3)
+	line := output[filepath.Join(dir, "a.txt")+":2"].(*mapreduce.Line)
+	assert.Equal(line.Text, "beta")
+	line = output[filepath.Join(dir, "b.txt")+":1"].(*mapreduce.Line)
+	assert.Equal(line.Text, "gamma")
+
+	err = mapreduce.MapReduce(PassThrough{},
+		mapreduce.WithSource(mapreduce.FromDir(filepath.Join(dir, "missing"), "")),
+		mapreduce.WithSink(mapreduce.ToMap(output)),
+	)
+	assert.ErrorMatch(err, ".*cannot read directory.*")
+}
+
+// TestSourceError tests stopping a job by a failing source.
+func TestSourceError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	source := func(ctx context.Context, emit mapreduce.IdentifiableChan) error {
+		for i := 0; i < 100; i++ {
+			emit <- &Number{i}
+		}
+		return errors.New("ouch")
+	}
+	var output []mapreduce.Identifiable
+
+	err := mapreduce.MapReduce(PassThrough{},
+		mapreduce.WithSource(source),
+		mapreduce.WithSink(mapreduce.ToSlice(&output)),
+	)
+	assert.ErrorMatch(err, "ouch")
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+
+	source = func(ctx context.Context, emit mapreduce.IdentifiableChan) error {
+		panic("ouch")
+	}
+	err = mapreduce.MapReduce(PassThrough{},
+		mapreduce.WithSource(source),
+		mapreduce.WithSink(mapreduce.ToSlice(&output)),
+	)
+	var pe *mapreduce.PanicError
+	assert.True(errors.As(err, &pe))
+	assert.Equal(pe.Stage, mapreduce.StageSource)
+}
+
+// TestSourceComposition tests sources and sinks in pipelines
+// and streams.
+func TestSourceComposition(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	var numbers []mapreduce.Identifiable
+	for i := 0; i < 100; i++ {
+		numbers = append(numbers, &Number{i})
+	}
+	var output []mapreduce.Identifiable
+
+	err := mapreduce.NewPipeline().
+		Stage(PassThrough{}, mapreduce.WithSource(mapreduce.FromSlice(numbers))).
+		Stage(PassThrough{}, mapreduce.WithSink(mapreduce.ToSlice(&output))).
+		Run(context.Background())
+	assert.Nil(err)
+	assert.Length(output, 100)
+
+	ss := NewStreamSummer(0, 0)
+	err = mapreduce.MapReduceStream(context.Background(), ss, mapreduce.CountWindows(10),
+		mapreduce.WithSource(mapreduce.FromSlice(numbers)),
+	)
+	assert.Nil(err)
+	assert.Length(ss.windows, 10)
+	assert.Equal(ss.sums[9], 945)
+
+	output = nil
+	err = mapreduce.MapReduceStream(context.Background(), ss, mapreduce.CountWindows(10),
+		mapreduce.WithSource(mapreduce.FromSlice(numbers)),
+		mapreduce.WithSink(mapreduce.ToSlice(&output)),
+	)
+	assert.Nil(err)
+	assert.Length(output, 10)
+	assert.Length(ss.windows, 10, "window consumer is not used")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// PassThrough maps and reduces the values unchanged. Input and
+// output are provided by sources and sinks.
+type PassThrough struct{}
+
+// Input is not used.
+func (pt PassThrough) Input() mapreduce.IdentifiableChan {
+	panic("not used")
+}
+
+// Map emits the value.
+func (pt PassThrough) Map(in mapreduce.Identifiable, emit mapreduce.IdentifiableChan) {
+	emit <- in
+}
+
+// Reduce emits the values.
+func (pt PassThrough) Reduce(in, emit mapreduce.IdentifiableChan) {
+	for kv := range in {
+		emit <- kv
+	}
+}
+
+// Consume is not used.
+func (pt PassThrough) Consume(in mapreduce.IdentifiableChan) error {
+	panic("not used")
+}
+
+// EOF
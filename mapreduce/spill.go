@@ -0,0 +1,427 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Spill
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"tideland.dev/go/trace/failure"
+)
+
+//--------------------
+// CODEC
+//--------------------
+
+// Codec serializes and deserializes the mapped data when
+// it is spilled to disk.
+type Codec interface {
+	// Encode serializes an identifiable value.
+	Encode(value Identifiable) ([]byte, error)
+
+	// Decode deserializes an identifiable value.
+	Decode(data []byte) (Identifiable, error)
+}
+
+//--------------------
+// SPILL
+//--------------------
+
+// spill shuffles the map emitted data on disk. It is encoded and
+// buffered per partition. Each time the spill limit is reached the
+// buffers are sorted by ID and written as runs. After the mapping
+// the runs of each partition are merged into its reduce channel. In
+// case of many runs they are merged into fewer ones first, so that
+// the number of open files is limited.
+func (j *job[In, M, Out]) spill(mapEmitChan chan M, reduceChans []chan M, handed []handover[M]) {
+	s, err := newSpiller(j.opts.spillDir, len(reduceChans))
+	if err != nil {
+		j.fail(err)
+		drain(mapEmitChan)
+		return
+	}
+	defer s.remove()
+
+	for kv := range mapEmitChan {
+		if j.ctx.Err() != nil {
+			continue
+		}
+		j.stats.emitted.Add(1)
+		id := j.mappedID(kv)
+		idx, ok := j.partition(id, len(reduceChans))
+		if !ok {
+			continue
+		}
+		data, err := j.encode(kv)
+		if err != nil {
+			j.fail(failure.Annotate(err, "cannot encode ID %q", id))
+			continue
+		}
+		s.add(idx, id, data)
+		if s.count >= j.opts.spillLimit {
+			if err := s.writeRuns(); err != nil {
+				j.fail(err)
+			}
+		}
+	}
+	if j.ctx.Err() != nil {
+		return
+	}
+
+	// Reduce the number of runs per partition, so that all
+	// partitions can be merged concurrently.
+	limit := max(1, spillFanIn/len(reduceChans))
+	for idx := range reduceChans {
+		if err := s.compact(j.ctx, idx, limit); err != nil {
+			j.fail(err)
+			return
+		}
+	}
+
+	// Merge the runs per partition.
+	var mwg sync.WaitGroup
+	for idx := range reduceChans {
+		mwg.Add(1)
+		go func(idx int) {
+			defer mwg.Done()
+			if err := s.merge(idx, func(id string, data []byte) bool {
+				kv, err := j.decode(data)
+				if err != nil {
+					j.fail(failure.Annotate(err, "cannot decode ID %q", id))
+					return false
+				}
+				handed[idx].store(kv)
+				select {
+				case <-j.ctx.Done():
+					return false
+				case reduceChans[idx] <- kv:
+					j.stats.reduced(idx)
+					return true
+				}
+			}); err != nil {
+				j.fail(err)
+			}
+		}(idx)
+	}
+	mwg.Wait()
+}
+
+//--------------------
+// SPILLER
+//--------------------
+
+// spillFanIn is the maximum number of runs merged at once.
+const spillFanIn = 64
+
+// record is one encoded value with its ID.
+type record struct {
+	id   string
+	data []byte
+}
+
+// spiller manages the buffers and the run files of the partitions.
+type spiller struct {
+	dir     string
+	buffers [][]record
+	runs    [][]string
+	count   int
+}
+
+// newSpiller creates the temporary directory for the run files.
+func newSpiller(dir string, size int) (*spiller, error) {
+	tmp, err := os.MkdirTemp(dir, "mapreduce-spill-")
+	if err != nil {
+		return nil, failure.Annotate(err, "cannot create spill directory")
+	}
+	return &spiller{
+		dir:     tmp,
+		buffers: make([][]record, size),
+		runs:    make([][]string, size),
+	}, nil
+}
+
+// add buffers an encoded value for a partition.
+func (s *spiller) add(idx int, id string, data []byte) {
+	s.buffers[idx] = append(s.buffers[idx], record{id, data})
+	s.count++
+}
+
+// writeRuns sorts the buffers and writes them as runs.
+func (s *spiller) writeRuns() error {
+	for idx, buffer := range s.buffers {
+		if len(buffer) == 0 {
+			continue
+		}
+		sortRecords(buffer)
+		filename := filepath.Join(s.dir, fmt.Sprintf("run-%d-%d", idx, len(s.runs[idx])))
+		if err := writeRun(filename, buffer); err != nil {
+			return err
+		}
+		s.runs[idx] = append(s.runs[idx], filename)
+		s.buffers[idx] = nil
+	}
+	s.count = 0
+	return nil
+}
+
+// compact merges groups of consecutive runs of a partition into new
+// runs until there are not more than the limit. Each pass merges up
+// to spillFanIn runs at once.
+func (s *spiller) compact(ctx context.Context, idx, limit int) error {
+	runs := s.runs[idx]
+	for pass := 0; len(runs) > limit; pass++ {
+		var compacted []string
+		for lo := 0; lo < len(runs); lo += spillFanIn {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			group := runs[lo:min(lo+spillFanIn, len(runs))]
+			if len(group) == 1 {
+				compacted = append(compacted, group[0])
+				continue
+			}
+			filename := filepath.Join(s.dir, fmt.Sprintf("run-%d-%d-%d", idx, pass, len(compacted)))
+			if err := mergeRunsToFile(group, filename); err != nil {
+				return err
+			}
+			compacted = append(compacted, filename)
+		}
+		runs = compacted
+	}
+	s.runs[idx] = runs
+	return nil
+}
+
+// merge reads the runs and the remaining buffer of a partition
+// in the order of the IDs and passes the records to the function.
+// Equal IDs keep the order of their emitting. Merging ends when
+// the function returns false.
+func (s *spiller) merge(idx int, f func(id string, data []byte) bool) error {
+	sortRecords(s.buffers[idx])
+	return mergeRuns(s.runs[idx], s.buffers[idx], f)
+}
+
+// remove deletes the temporary directory.
+func (s *spiller) remove() {
+	os.RemoveAll(s.dir)
+}
+
+//--------------------
+// RUNS
+//--------------------
+
+// mergeRuns reads the runs and the sorted buffer in the order of the
+// IDs and passes the records to the function. Equal IDs keep the order
+// of the runs, the buffer is the last one. Merging ends when the
+// function returns false.
+func mergeRuns(runs []string, buffer []record, f func(id string, data []byte) bool) error {
+	rh := &runHeap{}
+	for i, filename := range runs {
+		file, err := os.Open(filename)
+		if err != nil {
+			return failure.Annotate(err, "cannot open spill run")
+		}
+		defer file.Close()
+		rr := &runReader{order: i, reader: bufio.NewReader(file)}
+		if err := rh.push(rr); err != nil {
+			return err
+		}
+	}
+	rr := &runReader{order: len(runs), buffer: buffer}
+	if err := rh.push(rr); err != nil {
+		return err
+	}
+	for rh.Len() > 0 {
+		rr := (*rh)[0]
+		if !f(rr.current.id, rr.current.data) {
+			return nil
+		}
+		ok, err := rr.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(rh, 0)
+		} else {
+			heap.Pop(rh)
+		}
+	}
+	return nil
+}
+
+// mergeRunsToFile merges the runs into a new run file
+// and removes them afterwards.
+func mergeRunsToFile(runs []string, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return failure.Annotate(err, "cannot create spill run")
+	}
+	w := bufio.NewWriter(file)
+	buf := make([]byte, binary.MaxVarintLen64)
+	var werr error
+	if err := mergeRuns(runs, nil, func(id string, data []byte) bool {
+		werr = writeRecord(w, buf, record{id, data})
+		return werr == nil
+	}); err != nil {
+		file.Close()
+		return err
+	}
+	if werr == nil {
+		werr = w.Flush()
+	}
+	if werr != nil {
+		file.Close()
+		return failure.Annotate(werr, "cannot write spill run")
+	}
+	if err := file.Close(); err != nil {
+		return failure.Annotate(err, "cannot write spill run")
+	}
+	for _, run := range runs {
+		os.Remove(run)
+	}
+	return nil
+}
+
+// writeRun writes the records length prefixed into a file.
+func writeRun(filename string, records []record) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return failure.Annotate(err, "cannot create spill run")
+	}
+	w := bufio.NewWriter(file)
+	buf := make([]byte, binary.MaxVarintLen64)
+	for _, r := range records {
+		if err := writeRecord(w, buf, r); err != nil {
+			file.Close()
+			return failure.Annotate(err, "cannot write spill run")
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return failure.Annotate(err, "cannot write spill run")
+	}
+	return file.Close()
+}
+
+// writeRecord writes the ID and the data of the record length
+// prefixed using the buffer for the lengths.
+func writeRecord(w *bufio.Writer, buf []byte, r record) error {
+	for _, field := range [][]byte{[]byte(r.id), r.data} {
+		n := binary.PutUvarint(buf, uint64(len(field)))
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+		if _, err := w.Write(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runReader reads the records of one run file or of a buffer.
+type runReader struct {
+	order   int
+	reader  *bufio.Reader
+	buffer  []record
+	current record
+}
+
+// next reads the next record. It returns false at the end.
+func (rr *runReader) next() (bool, error) {
+	if rr.reader == nil {
+		if len(rr.buffer) == 0 {
+			return false, nil
+		}
+		rr.current = rr.buffer[0]
+		rr.buffer = rr.buffer[1:]
+		return true, nil
+	}
+	id, err := readField(rr.reader)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, failure.Annotate(err, "cannot read spill run")
+	}
+	data, err := readField(rr.reader)
+	if err != nil {
+		return false, failure.Annotate(err, "cannot read spill run")
+	}
+	rr.current = record{string(id), data}
+	return true, nil
+}
+
+// readField reads one length prefixed field.
+func readField(r *bufio.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	field := make([]byte, l)
+	if _, err := io.ReadFull(r, field); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return field, nil
+}
+
+// runHeap orders the run readers by their current IDs.
+type runHeap []*runReader
+
+func (rh runHeap) Len() int { return len(rh) }
+func (rh runHeap) Less(i, j int) bool {
+	if rh[i].current.id != rh[j].current.id {
+		return rh[i].current.id < rh[j].current.id
+	}
+	return rh[i].order < rh[j].order
+}
+func (rh runHeap) Swap(i, j int)       { rh[i], rh[j] = rh[j], rh[i] }
+func (rh *runHeap) Push(x interface{}) { *rh = append(*rh, x.(*runReader)) }
+func (rh *runHeap) Pop() interface{} {
+	old := *rh
+	n := len(old)
+	rr := old[n-1]
+	*rh = old[:n-1]
+	return rr
+}
+
+// push reads the first record of the run reader and
+// adds it to the heap if it is not empty.
+func (rh *runHeap) push(rr *runReader) error {
+	ok, err := rr.next()
+	if err != nil {
+		return err
+	}
+	if ok {
+		heap.Push(rh, rr)
+	}
+	return nil
+}
+
+// sortRecords sorts records stable by their IDs.
+func sortRecords(records []record) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].id < records[j].id
+	})
+}
+
+// EOF
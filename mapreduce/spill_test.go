@@ -0,0 +1,147 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestSpillMapReduce runs the grouped word count with
+// shuffling on disk.
+func TestSpillMapReduce(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	dir := t.TempDir()
+	wc := NewWordCounter(10000, assert)
+
+	err := mapreduce.MapReduce(mapreduce.Grouped(wc), mapreduce.WithSpill(WordCodec{}, dir, 1000))
+	assert.Nil(err)
+	assert.Equal(wc.counts, wc.expected, "all words are counted")
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(err)
+	assert.Empty(entries, "spill directory is removed")
+}
+
+// TestSpillMapReduceManyRuns tests the merging of more runs
+// than can be opened at once. About 1600 words spilled every
+// 10 words create more than 64 runs per partition.
+func TestSpillMapReduceManyRuns(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	dir := t.TempDir()
+	wc := NewWordCounter(300, assert)
+
+	err := mapreduce.MapReduce(mapreduce.Grouped(wc), mapreduce.WithSpill(WordCodec{}, dir, 10), mapreduce.WithReducers(4))
+	assert.Nil(err)
+	assert.Equal(wc.counts, wc.expected, "all words are counted")
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(err)
+	assert.Empty(entries, "spill directory is removed")
+}
+
+// TestSpillMapReduceOrder tests that the reducers receive
+// their values sorted by ID.
+func TestSpillMapReduceOrder(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	wc := &SortedWordCounter{NewWordCounter(1000, assert)}
+
+	err := mapreduce.MapReduce(wc, mapreduce.WithSpill(WordCodec{}, "", 100), mapreduce.WithReducers(3))
+	assert.Nil(err)
+	assert.Equal(wc.counts, wc.expected, "all words are counted")
+}
+
+// TestSpillMapReduceCodecError tests the stopping of a job
+// by a failing codec.
+func TestSpillMapReduceCodecError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	dir := t.TempDir()
+	wc := NewWordCounter(1000, assert)
+
+	err := mapreduce.MapReduce(mapreduce.Grouped(wc), mapreduce.WithSpill(FailingCodec{}, dir, 100))
+	assert.ErrorMatch(err, ".*cannot encode.*")
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(err)
+	assert.Empty(entries, "spill directory is removed")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// WordCodec encodes words as JSON.
+type WordCodec struct{}
+
+// Encode implements mapreduce.Codec.
+func (wc WordCodec) Encode(value mapreduce.Identifiable) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Decode implements mapreduce.Codec.
+func (wc WordCodec) Decode(data []byte) (mapreduce.Identifiable, error) {
+	var word Word
+	if err := json.Unmarshal(data, &word); err != nil {
+		return nil, err
+	}
+	return &word, nil
+}
+
+// FailingCodec always fails.
+type FailingCodec struct{}
+
+// Encode implements mapreduce.Codec.
+func (fc FailingCodec) Encode(value mapreduce.Identifiable) ([]byte, error) {
+	return nil, errors.New("ouch")
+}
+
+// Decode implements mapreduce.Codec.
+func (fc FailingCodec) Decode(data []byte) (mapreduce.Identifiable, error) {
+	return nil, errors.New("ouch")
+}
+
+// SortedWordCounter counts words in a reducer expecting
+// them sorted by ID.
+type SortedWordCounter struct {
+	*WordCounter
+}
+
+// Reduce counts the words while checking their order.
+func (swc *SortedWordCounter) Reduce(in, emit mapreduce.IdentifiableChan) {
+	var current *Word
+	for i := range in {
+		word := i.(*Word)
+		if current != nil && current.Word == word.Word {
+			current.Count += word.Count
+			continue
+		}
+		if current != nil {
+			swc.assert.True(current.Word < word.Word, "words are sorted")
+			emit <- current
+		}
+		current = &Word{word.Word, word.Count}
+	}
+	if current != nil {
+		emit <- current
+	}
+}
+
+// EOF
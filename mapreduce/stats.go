@@ -0,0 +1,134 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Statistics
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+//--------------------
+// STATISTICS
+//--------------------
+
+// Stats contains the statistics of a map/reduce job. The durations
+// of running phases contain the time elapsed so far.
+type Stats struct {
+	// Read is the number of values read from the input.
+	Read int64
+
+	// Mapped is the number of values processed by the mappers.
+	Mapped int64
+
+	// Emitted is the number of values emitted by the mappers
+	// or the combiners.
+	Emitted int64
+
+	// Reduced is the number of values passed to the reducers.
+	Reduced int64
+
+	// ReducerLoads contains the number of values passed to
+	// each reducer.
+	ReducerLoads []int64
+
+	// Consumed is the number of values passed to the consumer.
+	Consumed int64
+
+	// MapDuration is the time from the start of the job until
+	// the end of the mapping.
+	MapDuration time.Duration
+
+	// ReduceDuration is the time from the start of the job until
+	// the end of the reducing.
+	ReduceDuration time.Duration
+
+	// Duration is the time of the whole job.
+	Duration time.Duration
+}
+
+// stats collects the statistics while the job is running.
+type stats struct {
+	start        time.Time
+	read         atomic.Int64
+	mapped       atomic.Int64
+	emitted      atomic.Int64
+	reducerLoads []atomic.Int64
+	consumed     atomic.Int64
+	mapEnd       atomic.Int64
+	reduceEnd    atomic.Int64
+	end          atomic.Int64
+}
+
+// newStats creates the statistics for the number of reducers.
+func newStats(reducers int) *stats {
+	return &stats{
+		start:        time.Now(),
+		reducerLoads: make([]atomic.Int64, reducers),
+	}
+}
+
+// reduced counts a value passed to a reducer.
+func (s *stats) reduced(idx int) {
+	s.reducerLoads[idx].Add(1)
+}
+
+// finish stores the end time of a phase.
+func (s *stats) finish(end *atomic.Int64) {
+	end.Store(int64(time.Since(s.start)))
+}
+
+// snapshot returns the current statistics.
+func (s *stats) snapshot() Stats {
+	elapsed := time.Since(s.start)
+	duration := func(end *atomic.Int64) time.Duration {
+		if d := end.Load(); d > 0 {
+			return time.Duration(d)
+		}
+		return elapsed
+	}
+	st := Stats{
+		Read:           s.read.Load(),
+		Mapped:         s.mapped.Load(),
+		Emitted:        s.emitted.Load(),
+		ReducerLoads:   make([]int64, len(s.reducerLoads)),
+		Consumed:       s.consumed.Load(),
+		MapDuration:    duration(&s.mapEnd),
+		ReduceDuration: duration(&s.reduceEnd),
+		Duration:       duration(&s.end),
+	}
+	for i := range s.reducerLoads {
+		st.ReducerLoads[i] = s.reducerLoads[i].Load()
+		st.Reduced += st.ReducerLoads[i]
+	}
+	return st
+}
+
+//--------------------
+// PROGRESS
+//--------------------
+
+// reportProgress calls the progress function with the current
+// statistics in the interval until the done channel is closed.
+func (s *stats) reportProgress(interval time.Duration, progress func(stats Stats), done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			progress(s.snapshot())
+		}
+	}
+}
+
+// EOF
@@ -0,0 +1,90 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+	"time"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestStats tests the statistics of a job.
+func TestStats(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	mr := &NumberMapReducer{count: 10000}
+	var stats mapreduce.Stats
+
+	err := mapreduce.MapReduce(mr, mapreduce.WithReducers(4), mapreduce.WithStats(&stats))
+	assert.Nil(err)
+	assert.Equal(stats.Read, int64(10000))
+	assert.Equal(stats.Mapped, int64(10000))
+	assert.Equal(stats.Emitted, int64(10000))
+	assert.Equal(stats.Reduced, int64(10000))
+	assert.Equal(stats.Consumed, int64(10000))
+	assert.Length(stats.ReducerLoads, 4)
+	load := int64(0)
+	for _, l := range stats.ReducerLoads {
+		load += l
+	}
+	assert.Equal(load, stats.Reduced, "sum of reducer loads")
+	assert.True(stats.MapDuration > 0, "mapping took time")
+	assert.True(stats.MapDuration <= stats.ReduceDuration, "reducing ends after mapping")
+	assert.True(stats.ReduceDuration <= stats.Duration, "job ends after reducing")
+}
+
+// TestStatsCombiner tests the statistics of a job with
+// a combiner.
+func TestStatsCombiner(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	wc := &CombiningWordCounter{NewWordCounter(1000, assert)}
+	var stats mapreduce.Stats
+
+	err := mapreduce.MapReduce(mapreduce.Grouped(wc), mapreduce.WithStats(&stats))
+	assert.Nil(err)
+	assert.Equal(stats.Read, int64(1000))
+	assert.Equal(stats.Mapped, int64(1000))
+	assert.Equal(stats.Emitted, stats.Reduced)
+	assert.Equal(stats.Reduced, wc.reduced)
+	assert.Equal(stats.Consumed, int64(len(wc.expected)))
+}
+
+// TestProgress tests the progress reporting of a job.
+func TestProgress(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	mr := &NumberMapReducer{
+		count: 1000,
+		mapf: func(n *Number) error {
+			time.Sleep(100 * time.Microsecond)
+			return nil
+		},
+	}
+	var reports []mapreduce.Stats
+
+	err := mapreduce.MapReduce(mr, mapreduce.WithMappers(1), mapreduce.WithProgress(10*time.Millisecond, func(stats mapreduce.Stats) {
+		reports = append(reports, stats)
+	}))
+	assert.Nil(err)
+	assert.True(len(reports) > 1, "progress has been reported")
+	for i := 1; i < len(reports); i++ {
+		assert.True(reports[i-1].Mapped <= reports[i].Mapped, "mapping progresses")
+	}
+	last := reports[len(reports)-1]
+	assert.Equal(last.Consumed, int64(1000), "last report is final")
+}
+
+// EOF
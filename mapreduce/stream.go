@@ -0,0 +1,384 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Stream
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//--------------------
+// WINDOWING
+//--------------------
+
+// Windowing defines how a stream is split into windows. Each
+// window is processed like an individual map/reduce job.
+type Windowing struct {
+	size       time.Duration
+	slide      time.Duration
+	count      int
+	countSlide int
+}
+
+// TumblingWindows splits the stream into consecutive windows
+// of the given duration.
+func TumblingWindows(size time.Duration) Windowing {
+	return SlidingWindows(size, size)
+}
+
+// SlidingWindows starts a new window of the given size each time the
+// slide duration elapsed. So overlapping windows receive the same values.
+func SlidingWindows(size, slide time.Duration) Windowing {
+	if size <= 0 {
+		size = time.Second
+	}
+	if slide <= 0 {
+		slide = size
+	}
+	return Windowing{
+		size:  size,
+		slide: slide,
+	}
+}
+
+// CountWindows splits the stream into consecutive windows
+// of the given number of values.
+func CountWindows(count int) Windowing {
+	return SlidingCountWindows(count, count)
+}
+
+// SlidingCountWindows starts a new window of the given number of
+// values each time the slide number of values has been read. So
+// overlapping windows receive the same values.
+func SlidingCountWindows(count, slide int) Windowing {
+	count = atLeast(count, 1)
+	if slide < 1 {
+		slide = count
+	}
+	return Windowing{
+		count:      count,
+		countSlide: slide,
+	}
+}
+
+// Window describes one processed window of a stream. In case of time
+// based windows the end is the planned one, in case of count based
+// windows the time of the last value.
+type Window struct {
+	Index int
+	Start time.Time
+	End   time.Time
+	Count int
+}
+
+// WindowConsumer can be implemented by a MapReducer additionally. When
+// streaming ConsumeWindow() is called instead of Consume() for the
+// results of each window.
+type WindowConsumer interface {
+	// ConsumeWindow consumes the processed data of one window.
+	ConsumeWindow(w Window, in IdentifiableChan) error
+}
+
+//--------------------
+// STREAM
+//--------------------
+
+// MapReduceStream processes a never-ending input in windows. Each window
+// is mapped and reduced like an own job with the passed options. The
+// results of the windows are consumed one after another in the order of
+// the windows. The stream ends when the input is closed and all windows
+// are consumed, the context is cancelled, or an error occurs.
+//
+// The statistics of WithStats() and WithProgress() cover the whole stream.
+// The counters are the sums of all windows and the durations are measured
+// from the start of the stream. As windows are mapped and reduced all the
+// time, the phases end together with the stream.
+func MapReduceStream(ctx context.Context, mr MapReducer, windowing Windowing, options ...Option) error {
+	s := &stream{
+		mr:        mr,
+		windowing: windowing,
+		options:   options,
+		opts:      newOptions(options),
+	}
+	s.stats = newStats(s.opts.reducers)
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	defer s.cancel()
+
+	progressDone := make(chan struct{})
+	progressStopped := make(chan struct{})
+	if s.opts.progress != nil {
+		go func() {
+			defer close(progressStopped)
+			s.stats.reportProgress(s.opts.progressInterval, s.opts.progress, progressDone)
+		}()
+	} else {
+		close(progressStopped)
+	}
+
+	s.run()
+
+	s.stats.finish(&s.stats.mapEnd)
+	s.stats.finish(&s.stats.reduceEnd)
+	s.stats.finish(&s.stats.end)
+	close(progressDone)
+	<-progressStopped
+	if s.opts.progress != nil {
+		s.opts.progress(s.stats.snapshot())
+	}
+	if s.opts.stats != nil {
+		*s.opts.stats = s.stats.snapshot()
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+	return ctx.Err()
+}
+
+// streamWindow is one window of the stream with its job.
+type streamWindow struct {
+	info    Window
+	input   IdentifiableChan
+	closed  bool
+	results []Identifiable
+	done    chan struct{}
+}
+
+// stream contains the runtime environment of a stream.
+type stream struct {
+	ctx       context.Context
+	cancel    func()
+	mr        MapReducer
+	windowing Windowing
+	options   []Option
+	opts      *options
+	stats     *stats
+	windows   []*streamWindow
+	index     int
+	read      int
+	mu        sync.Mutex
+	err       error
+}
+
+// run reads the input, opens and closes the windows, and
+// delivers their results.
+func (s *stream) run() {
+	var input IdentifiableChan
+	if s.opts.source != nil {
+		input = runSource(s.ctx, s.opts.source, func(err error) {
+			if err != nil {
+				s.fail(err)
+			}
+		})
+	} else {
+		input = s.mr.Input()
+	}
+	timed := s.windowing.count == 0
+	var timer *time.Timer
+	var timerC <-chan time.Time
+	var nextOpen time.Time
+	if timed {
+		// Open the first windows before reading any value.
+		now := time.Now()
+		nextOpen = s.tick(now, now)
+		timer = time.NewTimer(s.nextTick(nextOpen))
+		defer timer.Stop()
+		timerC = timer.C
+	}
+	for input != nil || len(s.windows) > 0 {
+		var headDone chan struct{}
+		if len(s.windows) > 0 && s.windows[0].closed {
+			headDone = s.windows[0].done
+		}
+		select {
+		case <-s.ctx.Done():
+			s.shutdown(input)
+			return
+		case now := <-timerC:
+			nextOpen = s.tick(now, nextOpen)
+			timer.Reset(s.nextTick(nextOpen))
+		case kv, ok := <-input:
+			if !ok {
+				input = nil
+				timerC = nil
+				s.closeWindows(func(w *streamWindow) bool { return true })
+				continue
+			}
+			s.feed(kv)
+		case <-headDone:
+			if s.ctx.Err() != nil {
+				// Job of the window failed.
+				continue
+			}
+			w := s.windows[0]
+			s.windows = s.windows[1:]
+			if err := s.deliver(w); err != nil {
+				s.fail(err)
+			}
+		}
+	}
+}
+
+// tick closes the windows whose time is over and opens new ones.
+// It returns the time for the next opening.
+func (s *stream) tick(now, nextOpen time.Time) time.Time {
+	s.closeWindows(func(w *streamWindow) bool {
+		return !w.info.End.After(now)
+	})
+	for !nextOpen.After(now) {
+		s.open(nextOpen, nextOpen.Add(s.windowing.size))
+		nextOpen = nextOpen.Add(s.windowing.slide)
+	}
+	return nextOpen
+}
+
+// nextTick returns the duration until the next opening or closing.
+func (s *stream) nextTick(nextOpen time.Time) time.Duration {
+	next := nextOpen
+	for _, w := range s.windows {
+		if !w.closed && w.info.End.Before(next) {
+			next = w.info.End
+		}
+	}
+	return time.Until(next)
+}
+
+// feed passes a value to all open windows. In case of count based
+// windows new ones are opened and full ones closed.
+func (s *stream) feed(kv Identifiable) {
+	if s.windowing.count > 0 && s.read%s.windowing.countSlide == 0 {
+		s.open(time.Now(), time.Time{})
+	}
+	s.read++
+	for _, w := range s.windows {
+		if w.closed {
+			continue
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case w.input <- kv:
+			w.info.Count++
+		}
+	}
+	if s.windowing.count > 0 {
+		s.closeWindows(func(w *streamWindow) bool {
+			return w.info.Count >= s.windowing.count
+		})
+	}
+}
+
+// open starts the job of a new window.
+func (s *stream) open(start, end time.Time) {
+	w := &streamWindow{
+		info: Window{
+			Index: s.index,
+			Start: start,
+			End:   end,
+		},
+		input: make(IdentifiableChan),
+		done:  make(chan struct{}),
+	}
+	s.index++
+	s.windows = append(s.windows, w)
+
+	// All jobs count into the statistics of the stream,
+	// which are finished and reported by the stream itself.
+	j := newMapReducerJob(s.ctx, s.mr, s.options)
+	j.stats = s.stats
+	j.shared = true
+	j.opts.stats = nil
+	j.opts.progress = nil
+	j.input = func() <-chan Identifiable {
+		return w.input
+	}
+	j.consume = func(in chan Identifiable) error {
+		for kv := range in {
+			w.results = append(w.results, kv)
+		}
+		return nil
+	}
+	go func() {
+		defer close(w.done)
+		if err := j.run(); err != nil {
+			s.fail(err)
+		}
+	}()
+}
+
+// closeWindows closes the input of the open windows matching
+// the passed function.
+func (s *stream) closeWindows(match func(w *streamWindow) bool) {
+	for _, w := range s.windows {
+		if !w.closed && match(w) {
+			if s.windowing.count > 0 {
+				w.info.End = time.Now()
+			}
+			w.input.Close()
+			w.closed = true
+		}
+	}
+}
+
+// deliver passes the results of a window to the consumer.
+func (s *stream) deliver(w *streamWindow) error {
+	results := make(IdentifiableChan)
+	go func() {
+		defer results.Close()
+		for _, kv := range w.results {
+			select {
+			case <-s.ctx.Done():
+				return
+			case results <- kv:
+			}
+		}
+	}()
+	defer drain(results)
+	return protect(StageConsume, staticID(""), func() error {
+		if s.opts.sink != nil {
+			return s.opts.sink(results)
+		}
+		if wc, ok := s.mr.(WindowConsumer); ok {
+			return wc.ConsumeWindow(w.info, results)
+		}
+		return s.mr.Consume(results)
+	})
+}
+
+// shutdown closes all windows and waits for the end of their jobs.
+func (s *stream) shutdown(input IdentifiableChan) {
+	s.closeWindows(func(w *streamWindow) bool { return true })
+	for _, w := range s.windows {
+		<-w.done
+	}
+	if input != nil {
+		go drain(input)
+	}
+}
+
+// fail stores the first error of the stream and cancels it. Errors
+// of jobs returning due to the cancellation are ignored.
+func (s *stream) fail(err error) {
+	if ctxErr := s.ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+		return
+	}
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.cancel()
+}
+
+// EOF
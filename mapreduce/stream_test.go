@@ -0,0 +1,274 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestStreamCountWindows tests streaming with count based windows.
+func TestStreamCountWindows(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	ss := NewStreamSummer(100, 0)
+
+	err := mapreduce.MapReduceStream(context.Background(), ss, mapreduce.CountWindows(10))
+	assert.Nil(err)
+	assert.Length(ss.windows, 10)
+	for i, w := range ss.windows {
+		assert.Equal(w.Index, i)
+		assert.Equal(w.Count, 10)
+		assert.False(w.End.Before(w.Start), "window ends after start")
+		// Sum of 10*i to 10*i+9.
+		assert.Equal(ss.sums[i], 100*i+45)
+	}
+}
+
+// TestStreamSlidingCountWindows tests streaming with sliding
+// count based windows.
+func TestStreamSlidingCountWindows(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	ss := NewStreamSummer(100, 0)
+
+	err := mapreduce.MapReduceStream(context.Background(), ss, mapreduce.SlidingCountWindows(10, 5))
+	assert.Nil(err)
+	assert.Length(ss.windows, 20)
+	for i, w := range ss.windows {
+		assert.Equal(w.Index, i)
+		sum := 0
+		count := 0
+		for n := 5 * i; n < 5*i+10 && n < 100; n++ {
+			sum += n
+			count++
+		}
+		assert.Equal(w.Count, count)
+		assert.Equal(ss.sums[i], sum)
+	}
+}
+
+// TestStreamTumblingWindows tests streaming with time based windows.
+func TestStreamTumblingWindows(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	ss := NewStreamSummer(100, time.Millisecond)
+
+	err := mapreduce.MapReduceStream(context.Background(), ss, mapreduce.TumblingWindows(20*time.Millisecond))
+	assert.Nil(err)
+	assert.True(len(ss.windows) > 1, "multiple windows")
+	count := 0
+	sum := 0
+	for i, w := range ss.windows {
+		assert.Equal(w.Index, i)
+		assert.Equal(w.End.Sub(w.Start), 20*time.Millisecond)
+		if i > 0 {
+			assert.Equal(w.Start, ss.windows[i-1].End, "windows are consecutive")
+		}
+		count += w.Count
+		sum += ss.sums[i]
+	}
+	assert.Equal(count, 100)
+	assert.Equal(sum, 4950)
+}
+
+// TestStreamSlidingWindows tests streaming with sliding time
+// based windows.
+func TestStreamSlidingWindows(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	ss := NewStreamSummer(100, time.Millisecond)
+
+	err := mapreduce.MapReduceStream(context.Background(), ss, mapreduce.SlidingWindows(20*time.Millisecond, 10*time.Millisecond))
+	assert.Nil(err)
+	count := 0
+	for i, w := range ss.windows {
+		assert.Equal(w.Index, i)
+		if i > 0 {
+			assert.Equal(w.Start.Sub(ss.windows[i-1].Start), 10*time.Millisecond)
+		}
+		count += w.Count
+	}
+	assert.True(count > 100, "values are in overlapping windows")
+	assert.True(count <= 200, "values are at most in two windows")
+}
+
+// TestStreamStartup tests that no value is lost before
+// the first time based window is opened.
+func TestStreamStartup(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	for i := 0; i < 50; i++ {
+		ss := NewStreamSummer(100, 0)
+
+		err := mapreduce.MapReduceStream(context.Background(), ss, mapreduce.TumblingWindows(time.Hour))
+		assert.Nil(err)
+		assert.Length(ss.windows, 1)
+		assert.Equal(ss.windows[0].Count, 100)
+		assert.Equal(ss.sums[0], 4950)
+	}
+}
+
+// TestStreamStats tests the statistics of a stream, which
+// cover all windows.
+func TestStreamStats(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	ss := NewStreamSummer(100, 100*time.Microsecond)
+	var stats mapreduce.Stats
+	var reports []mapreduce.Stats
+
+	err := mapreduce.MapReduceStream(context.Background(), ss, mapreduce.CountWindows(10),
+		mapreduce.WithStats(&stats),
+		mapreduce.WithProgress(time.Millisecond, func(stats mapreduce.Stats) {
+			reports = append(reports, stats)
+		}))
+	assert.Nil(err)
+	assert.Length(ss.windows, 10)
+	assert.Equal(stats.Read, int64(100))
+	assert.Equal(stats.Mapped, int64(100))
+	assert.Equal(stats.Reduced, int64(100))
+	assert.Equal(stats.Consumed, int64(10))
+	assert.True(len(reports) > 0, "progress has been reported")
+	for i := 1; i < len(reports); i++ {
+		assert.True(reports[i-1].Read <= reports[i].Read, "reading progresses")
+		progresses := reports[i-1].Duration < reports[i].Duration || reports[i].Duration == stats.Duration
+		assert.True(progresses, "duration progresses until the end")
+	}
+	assert.Equal(reports[len(reports)-1], stats, "last report is final")
+	assert.True(stats.MapDuration <= stats.ReduceDuration, "mapping ends before reducing")
+	assert.True(stats.ReduceDuration <= stats.Duration, "reducing ends before the stream")
+	assert.True(stats.Duration >= 10*time.Millisecond, "duration covers the whole stream")
+}
+
+// TestStreamError tests the stopping of a stream by an error.
+func TestStreamError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	ss := NewStreamSummer(100, 0)
+	ss.err = errors.New("ouch")
+
+	err := mapreduce.MapReduceStream(context.Background(), ss, mapreduce.CountWindows(10))
+	assert.ErrorMatch(err, "ouch")
+	assert.Length(ss.windows, 3)
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
+// TestStreamCancel tests the cancellation of an unbounded stream.
+func TestStreamCancel(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	goroutines := runtime.NumGoroutine()
+	ss := NewStreamSummer(-1, 0)
+	ss.stop = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	ss.consumed = func(w mapreduce.Window) {
+		if w.Index == 5 {
+			cancel()
+			close(ss.stop)
+		}
+	}
+
+	err := mapreduce.MapReduceStream(ctx, ss, mapreduce.CountWindows(10))
+	assert.True(errors.Is(err, context.Canceled))
+	assert.True(len(ss.windows) >= 6, "windows until cancellation")
+	assert.True(waitGoroutines(goroutines), "all goroutines terminated")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// StreamSummer sums streamed numbers per window.
+type StreamSummer struct {
+	count    int
+	delay    time.Duration
+	err      error
+	consumed func(w mapreduce.Window)
+	windows  []mapreduce.Window
+	sums     []int
+	stop     chan struct{}
+}
+
+// NewStreamSummer creates a summer for the numbers from 0 to count-1.
+// A negative count produces numbers until stop is closed.
+func NewStreamSummer(count int, delay time.Duration) *StreamSummer {
+	return &StreamSummer{
+		count: count,
+		delay: delay,
+	}
+}
+
+// Input produces the numbers with the configured delay.
+func (ss *StreamSummer) Input() mapreduce.IdentifiableChan {
+	input := make(mapreduce.IdentifiableChan)
+	go func() {
+		defer close(input)
+		for i := 0; ss.count < 0 || i < ss.count; i++ {
+			if ss.delay > 0 {
+				time.Sleep(ss.delay)
+			}
+			select {
+			case <-ss.stop:
+				return
+			case input <- &Number{i}:
+			}
+		}
+	}()
+	return input
+}
+
+// Map emits the numbers under one ID.
+func (ss *StreamSummer) Map(in mapreduce.Identifiable, emit mapreduce.IdentifiableChan) {
+	emit <- &Word{"sum", in.(*Number).Value}
+}
+
+// Reduce sums the numbers.
+func (ss *StreamSummer) Reduce(in, emit mapreduce.IdentifiableChan) {
+	sum := &Word{"sum", 0}
+	found := false
+	for i := range in {
+		sum.Count += i.(*Word).Count
+		found = true
+	}
+	if found {
+		emit <- sum
+	}
+}
+
+// Consume is not used, see ConsumeWindow.
+func (ss *StreamSummer) Consume(in mapreduce.IdentifiableChan) error {
+	panic("not used")
+}
+
+// ConsumeWindow collects the window and its sum.
+func (ss *StreamSummer) ConsumeWindow(w mapreduce.Window, in mapreduce.IdentifiableChan) error {
+	sum := 0
+	for i := range in {
+		sum += i.(*Word).Count
+	}
+	ss.windows = append(ss.windows, w)
+	ss.sums = append(ss.sums, sum)
+	if ss.consumed != nil {
+		ss.consumed(w)
+	}
+	if ss.err != nil && w.Index == 2 {
+		return ss.err
+	}
+	return nil
+}
+
+// EOF
@@ -0,0 +1,174 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Typed
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce // import "tideland.dev/go/dsa/mapreduce"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"tideland.dev/go/trace/failure"
+)
+
+//--------------------
+// TYPED MAP/REDUCE
+//--------------------
+
+// MapFunc maps one input value to any number of key/value pairs
+// passed to emit. Returning an error stops the job.
+type MapFunc[In any, K comparable, V any] func(in In, emit func(key K, value V)) error
+
+// ReduceFunc reduces all values of one key to any number of output
+// values passed to emit. Returning an error stops the job.
+type ReduceFunc[K comparable, V any, Out any] func(key K, values []V, emit func(out Out)) error
+
+// ConsumeFunc consumes one output value. Returning an error stops the job.
+type ConsumeFunc[Out any] func(out Out) error
+
+// MapReduceTyped applies the typed map and reduce functions to the values
+// read from the input channel until it is closed. The reduced values are
+// passed to the consume function. The keys are converted into IDs for the
+// distribution to the reducers, so the partitioner works on their string
+// representation. It runs on the same engine as MapReduceContext(), so the
+// options as well as the behavior on cancellation and errors are the same.
+// Only WithSpill(), WithSource(), and WithSink() need a MapReducer and let
+// it fail. WithDeterministicOutput() always sorts the results by the IDs
+// of their keys, its less function is not used.
+func MapReduceTyped[In any, K comparable, V any, Out any](
+	ctx context.Context,
+	input <-chan In,
+	mapf MapFunc[In, K, V],
+	reducef ReduceFunc[K, V, Out],
+	consume ConsumeFunc[Out],
+	options ...Option,
+) error {
+	j := newJob[In, typedPair[K, V], typedValue[Out]](ctx, options)
+	switch {
+	case j.opts.codec != nil:
+		return failure.New("typed map/reduce cannot spill to disk")
+	case j.opts.source != nil:
+		return failure.New("typed map/reduce cannot read from a source")
+	case j.opts.sink != nil:
+		return failure.New("typed map/reduce cannot write to a sink")
+	}
+	j.inputID = func(in In) string {
+		return fmt.Sprint(in)
+	}
+	j.mappedID = func(tp typedPair[K, V]) string {
+		return tp.id
+	}
+	j.outputID = func(tv typedValue[Out]) string {
+		return tv.id
+	}
+	j.input = func() <-chan In {
+		return input
+	}
+	j.mapf = func(ctx context.Context, in In, emit chan typedPair[K, V]) error {
+		return mapf(in, func(key K, value V) {
+			emit <- typedPair[K, V]{keyID(key), key, value}
+		})
+	}
+	j.reducef = func(ctx context.Context, in chan typedPair[K, V], emit chan typedValue[Out]) error {
+		return reduceTyped(ctx, reducef, in, emit)
+	}
+	j.consume = func(in chan typedValue[Out]) error {
+		for tv := range in {
+			if err := consume(tv.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if j.opts.ordered {
+		j.less = func(a, b typedValue[Out]) bool {
+			return a.id < b.id
+		}
+	}
+	return j.run()
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// typedPair contains the key/value pairs emitted by the mapping.
+type typedPair[K comparable, V any] struct {
+	id    string
+	key   K
+	value V
+}
+
+// typedValue contains the reduced values with the IDs of their keys.
+type typedValue[T any] struct {
+	id    string
+	value T
+}
+
+// reduceTyped groups the pairs by their IDs and, in case of equal
+// string representations of different keys, by the keys themselves.
+// The groups are passed to the reduce function sorted by their IDs.
+func reduceTyped[K comparable, V any, Out any](
+	ctx context.Context,
+	reducef ReduceFunc[K, V, Out],
+	in chan typedPair[K, V],
+	emit chan typedValue[Out],
+) error {
+	type group struct {
+		id     string
+		key    K
+		values []V
+	}
+	var groups []*group
+	index := make(map[K]*group)
+	for pair := range in {
+		g, ok := index[pair.key]
+		if !ok {
+			g = &group{id: pair.id, key: pair.key}
+			index[pair.key] = g
+			groups = append(groups, g)
+		}
+		g.values = append(g.values, pair.value)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].id < groups[j].id
+	})
+	for _, g := range groups {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		id := g.id
+		err := protect(StageReduce, staticID(id), func() error {
+			return reducef(g.key, g.values, func(out Out) {
+				emit <- typedValue[Out]{id, out}
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyID returns the string representation of a key.
+func keyID[K comparable](key K) string {
+	switch k := any(key).(type) {
+	case string:
+		return k
+	case int:
+		return strconv.Itoa(k)
+	default:
+		return fmt.Sprint(key)
+	}
+}
+
+// EOF
@@ -0,0 +1,204 @@
+// Tideland Go Data Structures and Algorithms - Map/Reduce - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package mapreduce_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/mapreduce"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestMapReduceTyped runs a typed word count.
+func TestMapReduceTyped(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	lines := []string{
+		"alpha bravo charlie",
+		"bravo charlie",
+		"charlie",
+	}
+	counts := make(map[string]int)
+
+	err := mapreduce.MapReduceTyped(
+		context.Background(),
+		feed(lines),
+		func(line string, emit func(string, int)) error {
+			for _, word := range strings.Fields(line) {
+				emit(word, 1)
+			}
+			return nil
+		},
+		func(word string, ones []int, emit func(WordCount)) error {
+			emit(WordCount{word, len(ones)})
+			return nil
+		},
+		func(wc WordCount) error {
+			counts[wc.Word] = wc.Count
+			return nil
+		},
+	)
+	assert.Nil(err)
+	assert.Equal(counts, map[string]int{"alpha": 1, "bravo": 2, "charlie": 3})
+}
+
+// TestMapReduceTypedKeys tests that different keys with equal
+// string representations are reduced separately.
+func TestMapReduceTypedKeys(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	type key struct {
+		a string
+		b string
+	}
+	keys := []key{{"a", " b"}, {"a ", "b"}, {"a", " b"}}
+	counts := make(map[key]int)
+
+	err := mapreduce.MapReduceTyped(
+		context.Background(),
+		feed(keys),
+		func(k key, emit func(key, int)) error {
+			emit(k, 1)
+			return nil
+		},
+		func(k key, ones []int, emit func(key)) error {
+			for range ones {
+				emit(k)
+			}
+			return nil
+		},
+		func(k key) error {
+			counts[k]++
+			return nil
+		},
+		mapreduce.WithReducers(1),
+	)
+	assert.Nil(err)
+	assert.Equal(counts, map[key]int{{"a", " b"}: 2, {"a ", "b"}: 1})
+}
+
+// TestMapReduceTypedError tests the stopping of a typed
+// job by an error.
+func TestMapReduceTypedError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	numbers := make([]int, 10000)
+	for i := range numbers {
+		numbers[i] = i
+	}
+
+	err := mapreduce.MapReduceTyped(
+		context.Background(),
+		feed(numbers),
+		func(n int, emit func(int, int)) error {
+			emit(n%10, n)
+			return nil
+		},
+		func(k int, ns []int, emit func(int)) error {
+			if k == 5 {
+				return errors.New("cannot reduce 5")
+			}
+			emit(len(ns))
+			return nil
+		},
+		func(n int) error {
+			return nil
+		},
+	)
+	assert.ErrorMatch(err, "cannot reduce 5")
+}
+
+// TestMapReduceTypedOrdered tests the deterministic output of
+// a typed job sorted by the IDs of the keys.
+func TestMapReduceTypedOrdered(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	var words []string
+
+	err := mapreduce.MapReduceTyped(
+		context.Background(),
+		feed([]string{"delta alpha", "charlie bravo alpha"}),
+		func(line string, emit func(string, int)) error {
+			for _, word := range strings.Fields(line) {
+				emit(word, 1)
+			}
+			return nil
+		},
+		func(word string, ones []int, emit func(string)) error {
+			emit(word)
+			return nil
+		},
+		func(word string) error {
+			words = append(words, word)
+			return nil
+		},
+		mapreduce.WithDeterministicOutput(nil),
+	)
+	assert.Nil(err)
+	assert.Equal(words, []string{"alpha", "bravo", "charlie", "delta"})
+}
+
+// TestMapReduceTypedOptions tests the rejection of the options
+// needing a MapReducer.
+func TestMapReduceTypedOptions(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	run := func(option mapreduce.Option) error {
+		return mapreduce.MapReduceTyped(
+			context.Background(),
+			feed([]int{1, 2, 3}),
+			func(n int, emit func(int, int)) error {
+				emit(n, n)
+				return nil
+			},
+			func(k int, ns []int, emit func(int)) error {
+				emit(k)
+				return nil
+			},
+			func(n int) error {
+				return nil
+			},
+			option,
+		)
+	}
+
+	assert.ErrorMatch(run(mapreduce.WithSpill(WordCodec{}, t.TempDir(), 10)), ".*cannot spill to disk")
+	assert.ErrorMatch(run(mapreduce.WithSink(func(in mapreduce.IdentifiableChan) error {
+		return nil
+	})), ".*cannot write to a sink")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// WordCount contains a word and its number.
+type WordCount struct {
+	Word  string
+	Count int
+}
+
+// feed returns a channel delivering the values.
+func feed[T any](values []T) <-chan T {
+	c := make(chan T)
+	go func() {
+		defer close(c)
+		for _, value := range values {
+			c <- value
+		}
+	}()
+	return c
+}
+
+// EOF
@@ -0,0 +1,102 @@
+// Tideland Go Data Structures and Algorithms - Sort - Context
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+//--------------------
+// CONTEXT SORTING
+//--------------------
+
+// SortContext sorts the data like Sort() but stops when the context
+// is cancelled and returns its error. In this case the data is left
+// partially sorted. Other than Sort() it uses a limited number of
+// goroutines, see WithWorkers().
+func SortContext(ctx context.Context, data sort.Interface, options ...Option) error {
+	return NewSorter(options...).SortContext(ctx, data)
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// contextQuickSort contains the state of a cancelable
+// quicksort with a limited number of goroutines.
+type contextQuickSort struct {
+	opts        *options
+	done        <-chan struct{}
+	data        sort.Interface
+	sem         chan struct{}
+	wg          sync.WaitGroup
+	interrupted atomic.Bool
+}
+
+// cancelled checks if the context is done and marks
+// the sorting as interrupted.
+func (qs *contextQuickSort) cancelled() bool {
+	select {
+	case <-qs.done:
+		qs.interrupted.Store(true)
+		return true
+	default:
+		return false
+	}
+}
+
+// sort partitions the data until the parts are small enough for
+// insertion sort. The lower parts of large partitions are passed
+// to new goroutines as long as the number of workers allows it,
+// otherwise they are sorted sequentially.
+func (qs *contextQuickSort) sort(lo, hi int) {
+	for hi-lo > qs.opts.thresholds.Sequential {
+		if qs.cancelled() {
+			return
+		}
+		plo, phi := partition(qs.data, lo, hi, qs.opts.pivot)
+		if plo-lo > qs.opts.thresholds.Parallel && qs.acquire() {
+			qs.wg.Add(1)
+			go func(lo, hi int) {
+				defer qs.wg.Done()
+				defer qs.release()
+				qs.sort(lo, hi)
+			}(lo, plo)
+		} else {
+			qs.sort(lo, plo)
+		}
+		lo = phi
+	}
+	if !qs.cancelled() {
+		insertionSort(qs.data, lo, hi)
+	}
+}
+
+// acquire tries to get a worker slot without blocking.
+func (qs *contextQuickSort) acquire() bool {
+	select {
+	case qs.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees a worker slot.
+func (qs *contextQuickSort) release() {
+	<-qs.sem
+}
+
+// EOF
@@ -0,0 +1,122 @@
+// Tideland Go Data Structures and Algorithms - Sort - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	stdsort "sort"
+	"sync/atomic"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/sort"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestSortContext tests sorting with different numbers of workers.
+func TestSortContext(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	par := sort.DefaultThresholds().Parallel
+	for _, size := range []int{0, 1, 100, 10*par + 3} {
+		for _, workers := range []int{0, 1, 2, 8} {
+			is := generateIntSlice(size)
+			expected := make([]int, size)
+			copy(expected, is)
+			stdsort.Ints(expected)
+
+			err := sort.SortContext(context.Background(), is, sort.WithWorkers(workers))
+			assert.Nil(err)
+			assert.Equal([]int(is), expected, fmt.Sprintf("size %d with %d workers", size, workers))
+		}
+	}
+}
+
+// TestSortContextWorkers tests the limit of concurrent goroutines.
+func TestSortContextWorkers(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	par := sort.DefaultThresholds().Parallel
+	for _, workers := range []int{1, 3} {
+		goroutines := runtime.NumGoroutine()
+		cs := &CountingSlice{IntSlice: generateIntSlice(50 * par)}
+
+		err := sort.SortContext(context.Background(), cs, sort.WithWorkers(workers))
+		assert.Nil(err)
+		assert.True(stdsort.IsSorted(cs), "sorted")
+		maxGoroutines := int(cs.maxGoroutines.Load())
+		assert.True(maxGoroutines <= goroutines+workers-1, fmt.Sprintf("%d goroutines with %d workers", maxGoroutines-goroutines, workers))
+	}
+}
+
+// TestSortContextCancel tests the cancellation of a sorting.
+func TestSortContextCancel(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	par := sort.DefaultThresholds().Parallel
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := sort.SortContext(ctx, generateIntSlice(10*par))
+	assert.True(errors.Is(err, context.Canceled))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	cs := &CountingSlice{
+		IntSlice: generateIntSlice(10 * par),
+		less: func(calls int64) {
+			if calls == 1000 {
+				cancel()
+			}
+		},
+	}
+
+	err = sort.SortContext(ctx, cs)
+	assert.True(errors.Is(err, context.Canceled))
+	assert.False(stdsort.IsSorted(cs), "sorting has been interrupted")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// CountingSlice counts the calls of Less and samples the
+// number of goroutines.
+type CountingSlice struct {
+	stdsort.IntSlice
+	calls         atomic.Int64
+	maxGoroutines atomic.Int64
+	less          func(calls int64)
+}
+
+// Less implements sort.Interface.
+func (cs *CountingSlice) Less(i, j int) bool {
+	calls := cs.calls.Add(1)
+	if cs.less != nil {
+		cs.less(calls)
+	}
+	if calls%1000 == 0 {
+		n := int64(runtime.NumGoroutine())
+		for {
+			max := cs.maxGoroutines.Load()
+			if n <= max || cs.maxGoroutines.CompareAndSwap(max, n) {
+				break
+			}
+		}
+	}
+	return cs.IntSlice.Less(i, j)
+}
+
+// EOF
@@ -7,6 +7,39 @@
 
 // Package sort provides a parallel quicksort. It uses the same interface
 // as the standard sort package.
+//
+// Slice() sorts any slice with a less function like its standard
+// counterpart. SliceFunc() and Ordered() do the same for typed slices
+// without the need of an adapter type.
+//
+// Stable() keeps the order of equal elements. It is a parallel merge
+// sort merging in place, so it needs no additional memory.
+//
+// SortContext() can be cancelled and limits the number of concurrently
+// sorting goroutines, which can be set with WithWorkers().
+//
+// A Sorter uses own thresholds for switching between the algorithms
+// and an own PivotStrategy. Calibrate() helps to find the thresholds
+// working best on the current machine.
+//
+// RadixInts(), RadixFloats(), and RadixStrings() sort without comparisons
+// using parallel radix sorts. Their variants with the suffix Func sort any
+// elements by a key returned by a function.
+//
+// NthElement(), PartialSort(), and TopK() only sort as much as needed
+// to select single or the smallest elements.
+//
+// Sorted data can be searched with LowerBound() and UpperBound(),
+// deduplicated with Unique(), and merged with Merge(). MergeRuns() and
+// MergeK() merge sorted runs of one collection in place.
+//
+// External() sorts records not fitting into the memory. They are read
+// and written with a Codec, sorted in chunks, and merged from temporary
+// files.
+//
+// Verify() checks sorting functions with the data of problematic
+// Patterns() like sorted, reversed, or equal values. Benchmark()
+// compares their speed with the standard library.
 package sort // import "tideland.dev/go/dsa/sort"
 
 // EOF
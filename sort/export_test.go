@@ -20,7 +20,7 @@ import (
 //--------------------
 
 func Partition(data sort.Interface, lo, hi int) (int, int) {
-	return partition(data, lo, hi)
+	return partition(data, lo, hi, NintherPivot)
 }
 
 func InsertionSort(data sort.Interface, lo, hi int) {
@@ -28,7 +28,7 @@ func InsertionSort(data sort.Interface, lo, hi int) {
 }
 
 func SequentialQuickSort(data sort.Interface, lo, hi int) {
-	sequentialQuickSort(data, lo, hi)
+	NewSorter().sequentialQuickSort(data, lo, hi)
 }
 
 // EOF
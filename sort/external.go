@@ -0,0 +1,374 @@
+// Tideland Go Data Structures and Algorithms - Sort - External
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tideland.dev/go/trace/failure"
+)
+
+//--------------------
+// CODEC
+//--------------------
+
+// Decoder reads records one by one. At the end of the
+// input it returns io.EOF.
+type Decoder[T any] interface {
+	Decode() (T, error)
+}
+
+// Encoder writes records one by one. Flush is called after
+// the last record.
+type Encoder[T any] interface {
+	Encode(record T) error
+	Flush() error
+}
+
+// Codec creates the decoders and encoders for the input, the
+// output, and the temporary runs of an external sort.
+type Codec[T any] interface {
+	NewDecoder(r io.Reader) Decoder[T]
+	NewEncoder(w io.Writer) Encoder[T]
+}
+
+// LineCodec reads and writes lines of text. The records are the
+// lines without their line endings.
+type LineCodec struct{}
+
+// NewDecoder implements Codec.
+func (lc LineCodec) NewDecoder(r io.Reader) Decoder[string] {
+	return &lineCoder{reader: bufio.NewReader(r)}
+}
+
+// NewEncoder implements Codec.
+func (lc LineCodec) NewEncoder(w io.Writer) Encoder[string] {
+	return &lineCoder{writer: bufio.NewWriter(w)}
+}
+
+// CSVCodec reads and writes CSV records. In case of a zero
+// comma the fields are separated by commas.
+type CSVCodec struct {
+	Comma rune
+}
+
+// NewDecoder implements Codec.
+func (cc CSVCodec) NewDecoder(r io.Reader) Decoder[[]string] {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	if cc.Comma != 0 {
+		reader.Comma = cc.Comma
+	}
+	return &csvCoder{reader: reader}
+}
+
+// NewEncoder implements Codec.
+func (cc CSVCodec) NewEncoder(w io.Writer) Encoder[[]string] {
+	writer := csv.NewWriter(w)
+	if cc.Comma != 0 {
+		writer.Comma = cc.Comma
+	}
+	return &csvCoder{writer: writer}
+}
+
+//--------------------
+// EXTERNAL SORT
+//--------------------
+
+// External sorts records too large for the memory. They are read from
+// r with the codec in chunks, see WithChunkSize(). Each chunk is sorted
+// with the parallel quicksort and written as run into a temporary file,
+// see WithTempDir(). Finally the runs are merged and written to w. In
+// case of many runs they are merged in multiple passes, so that only a
+// limited number of files is open at the same time. If the records fit
+// into one chunk no temporary files are used. Like Sort() the sorting
+// is not stable.
+func External[T any](r io.Reader, w io.Writer, codec Codec[T], less func(a, b T) bool, options ...Option) error {
+	s := NewSorter(options...)
+	decoder := codec.NewDecoder(r)
+	var runs []string
+	var dir string
+	var created int
+	defer func() {
+		if dir != "" {
+			os.RemoveAll(dir)
+		}
+	}()
+	for {
+		chunk, err := readChunk(decoder, s.opts.chunkSize)
+		if err != nil {
+			return err
+		}
+		s.Sort(&funcSlice[T]{chunk, less})
+		if len(runs) == 0 && len(chunk) < s.opts.chunkSize {
+			// All records fit into memory.
+			return writeRecords(codec.NewEncoder(w), chunk)
+		}
+		if len(chunk) == 0 {
+			break
+		}
+		if dir == "" {
+			dir, err = os.MkdirTemp(s.opts.tempDir, "sort-external-")
+			if err != nil {
+				return failure.Annotate(err, "cannot create directory for runs")
+			}
+		}
+		filename := filepath.Join(dir, fmt.Sprintf("run-%d", created))
+		created++
+		if err := writeRun(filename, codec, chunk); err != nil {
+			return err
+		}
+		runs = append(runs, filename)
+		if len(chunk) < s.opts.chunkSize {
+			break
+		}
+	}
+	// Merge groups of runs until they can be merged at once.
+	for len(runs) > mergeFanIn {
+		var merged []string
+		for lo := 0; lo < len(runs); lo += mergeFanIn {
+			group := runs[lo:min(lo+mergeFanIn, len(runs))]
+			if len(group) == 1 {
+				merged = append(merged, group[0])
+				continue
+			}
+			filename := filepath.Join(dir, fmt.Sprintf("run-%d", created))
+			created++
+			if err := mergeRunsToFile(group, filename, codec, less); err != nil {
+				return err
+			}
+			merged = append(merged, filename)
+		}
+		runs = merged
+	}
+	return mergeRuns(runs, w, codec, less)
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// mergeFanIn is the maximum number of runs merged at once.
+const mergeFanIn = 64
+
+// lineCoder implements Decoder and Encoder for lines.
+type lineCoder struct {
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+func (lc *lineCoder) Decode() (string, error) {
+	line, err := lc.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return line, err
+}
+
+func (lc *lineCoder) Encode(line string) error {
+	if _, err := lc.writer.WriteString(line); err != nil {
+		return err
+	}
+	return lc.writer.WriteByte('\n')
+}
+
+func (lc *lineCoder) Flush() error {
+	return lc.writer.Flush()
+}
+
+// csvCoder implements Decoder and Encoder for CSV records.
+type csvCoder struct {
+	reader *csv.Reader
+	writer *csv.Writer
+}
+
+func (cc *csvCoder) Decode() ([]string, error) {
+	return cc.reader.Read()
+}
+
+func (cc *csvCoder) Encode(record []string) error {
+	return cc.writer.Write(record)
+}
+
+func (cc *csvCoder) Flush() error {
+	cc.writer.Flush()
+	return cc.writer.Error()
+}
+
+// readChunk reads up to size records.
+func readChunk[T any](decoder Decoder[T], size int) ([]T, error) {
+	var chunk []T
+	for len(chunk) < size {
+		record, err := decoder.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, failure.Annotate(err, "cannot decode record")
+		}
+		chunk = append(chunk, record)
+	}
+	return chunk, nil
+}
+
+// writeRecords encodes all records and flushes the encoder.
+func writeRecords[T any](encoder Encoder[T], records []T) error {
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return failure.Annotate(err, "cannot encode record")
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return failure.Annotate(err, "cannot encode record")
+	}
+	return nil
+}
+
+// writeRun writes the sorted records into a run file.
+func writeRun[T any](filename string, codec Codec[T], records []T) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return failure.Annotate(err, "cannot create run")
+	}
+	if err := writeRecords(codec.NewEncoder(file), records); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return failure.Annotate(err, "cannot write run")
+	}
+	return nil
+}
+
+// mergeRunsToFile merges the runs into a new run file
+// and removes them afterwards.
+func mergeRunsToFile[T any](runs []string, filename string, codec Codec[T], less func(a, b T) bool) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return failure.Annotate(err, "cannot create run")
+	}
+	if err := mergeRuns(runs, file, codec, less); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return failure.Annotate(err, "cannot write run")
+	}
+	for _, run := range runs {
+		os.Remove(run)
+	}
+	return nil
+}
+
+// mergeRuns merges the runs in the order of the less function.
+func mergeRuns[T any](runs []string, w io.Writer, codec Codec[T], less func(a, b T) bool) error {
+	rh := &runHeap[T]{less: less}
+	for i, filename := range runs {
+		file, err := os.Open(filename)
+		if err != nil {
+			return failure.Annotate(err, "cannot open run")
+		}
+		defer file.Close()
+		rr := &runReader[T]{order: i, decoder: codec.NewDecoder(bufio.NewReader(file))}
+		ok, err := rr.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			rh.readers = append(rh.readers, rr)
+		}
+	}
+	heap.Init(rh)
+	encoder := codec.NewEncoder(w)
+	for rh.Len() > 0 {
+		rr := rh.readers[0]
+		if err := encoder.Encode(rr.current); err != nil {
+			return failure.Annotate(err, "cannot encode record")
+		}
+		ok, err := rr.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(rh, 0)
+		} else {
+			heap.Pop(rh)
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return failure.Annotate(err, "cannot encode record")
+	}
+	return nil
+}
+
+// runReader reads the records of one run.
+type runReader[T any] struct {
+	order   int
+	decoder Decoder[T]
+	current T
+}
+
+// next reads the next record. It returns false at the end of the run.
+func (rr *runReader[T]) next() (bool, error) {
+	record, err := rr.decoder.Decode()
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	}
+	if err != nil {
+		return false, failure.Annotate(err, "cannot read run")
+	}
+	rr.current = record
+	return true, nil
+}
+
+// runHeap orders the run readers by their current records. Equal
+// records are ordered by their runs.
+type runHeap[T any] struct {
+	readers []*runReader[T]
+	less    func(a, b T) bool
+}
+
+func (rh *runHeap[T]) Len() int { return len(rh.readers) }
+
+func (rh *runHeap[T]) Less(i, j int) bool {
+	a, b := rh.readers[i], rh.readers[j]
+	if rh.less(a.current, b.current) {
+		return true
+	}
+	if rh.less(b.current, a.current) {
+		return false
+	}
+	return a.order < b.order
+}
+
+func (rh *runHeap[T]) Swap(i, j int) { rh.readers[i], rh.readers[j] = rh.readers[j], rh.readers[i] }
+
+func (rh *runHeap[T]) Push(x any) { rh.readers = append(rh.readers, x.(*runReader[T])) }
+
+func (rh *runHeap[T]) Pop() any {
+	last := len(rh.readers) - 1
+	rr := rh.readers[last]
+	rh.readers = rh.readers[:last]
+	return rr
+}
+
+// EOF
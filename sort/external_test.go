@@ -0,0 +1,155 @@
+// Tideland Go Data Structures and Algorithms - Sort - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	stdsort "sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/sort"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestExternalLines tests sorting lines with different chunk sizes.
+func TestExternalLines(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	less := func(a, b string) bool { return a < b }
+	tests := []struct {
+		lines     int
+		chunkSize int
+	}{
+		// One run per line needs multiple merge passes.
+		{300, 1},
+		{10000, 999},
+		{10000, 1000},
+		{10000, 10000},
+		{10000, 20000},
+	}
+
+	for _, test := range tests {
+		lines := make([]string, test.lines)
+		for i := range lines {
+			lines[i] = generateString(rand.Intn(20), "abcdefghij")
+		}
+		input := strings.Join(lines, "\n") + "\n"
+		expected := make([]string, len(lines))
+		copy(expected, lines)
+		stdsort.Strings(expected)
+		dir := t.TempDir()
+		var output bytes.Buffer
+
+		err := sort.External[string](strings.NewReader(input), &output, sort.LineCodec{}, less,
+			sort.WithChunkSize(test.chunkSize),
+			sort.WithTempDir(dir),
+		)
+		assert.Nil(err)
+		assert.Equal(output.String(), strings.Join(expected, "\n")+"\n", fmt.Sprintf("%d lines with chunk size %d", test.lines, test.chunkSize))
+		entries, err := os.ReadDir(dir)
+		assert.Nil(err)
+		assert.Empty(entries, "runs are removed")
+	}
+}
+
+// TestExternalCSV tests sorting CSV records by a numeric column.
+func TestExternalCSV(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	var input strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&input, "\"name, %d\";%d\n", i, rand.Intn(1000))
+	}
+	byValue := func(a, b []string) bool {
+		av, _ := strconv.Atoi(a[1])
+		bv, _ := strconv.Atoi(b[1])
+		return av < bv
+	}
+	var output bytes.Buffer
+
+	err := sort.External[[]string](strings.NewReader(input.String()), &output, sort.CSVCodec{Comma: ';'}, byValue,
+		sort.WithChunkSize(128),
+	)
+	assert.Nil(err)
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Length(lines, 5000)
+	last := -1
+	for _, line := range lines {
+		value, err := strconv.Atoi(line[strings.LastIndex(line, ";")+1:])
+		assert.Nil(err)
+		assert.True(value >= last, "sorted by value")
+		assert.Match(line, `^name, \d+;\d+$`)
+		last = value
+	}
+}
+
+// TestExternalEmpty tests sorting an empty input.
+func TestExternalEmpty(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	var output bytes.Buffer
+
+	err := sort.External[string](strings.NewReader(""), &output, sort.LineCodec{}, func(a, b string) bool { return a < b })
+	assert.Nil(err)
+	assert.Equal(output.Len(), 0)
+}
+
+// TestExternalErrors tests failing reading and writing.
+func TestExternalErrors(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	less := func(a, b string) bool { return a < b }
+	input := io.MultiReader(strings.NewReader("b\na\n"), FailingReader{})
+
+	err := sort.External[string](input, io.Discard, sort.LineCodec{}, less)
+	assert.ErrorMatch(err, ".*cannot decode record.*ouch.*")
+
+	err = sort.External[string](strings.NewReader("b\na\nc\n"), FailingWriter{}, sort.LineCodec{}, less,
+		sort.WithChunkSize(1),
+	)
+	assert.ErrorMatch(err, ".*cannot encode record.*ouch.*")
+
+	err = sort.External[string](strings.NewReader("b\na\nc\n"), io.Discard, sort.LineCodec{}, less,
+		sort.WithChunkSize(1),
+		sort.WithTempDir("/does/not/exist"),
+	)
+	assert.ErrorMatch(err, ".*cannot create directory for runs.*")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// FailingReader returns an error on each read.
+type FailingReader struct{}
+
+// Read implements io.Reader.
+func (fr FailingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("ouch")
+}
+
+// FailingWriter returns an error on each write.
+type FailingWriter struct{}
+
+// Write implements io.Writer.
+func (fw FailingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("ouch")
+}
+
+// EOF
@@ -0,0 +1,106 @@
+// Tideland Go Data Structures and Algorithms - Sort - Options
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"runtime"
+)
+
+//--------------------
+// OPTIONS
+//--------------------
+
+// options contains the configuration of a sorting.
+type options struct {
+	thresholds Thresholds
+	pivot      PivotStrategy
+	workers    int
+	chunkSize  int
+	tempDir    string
+}
+
+// newOptions returns the default options modified by
+// the passed ones.
+func newOptions(opts []Option) *options {
+	o := &options{
+		thresholds: DefaultThresholds(),
+		pivot:      NintherPivot,
+		workers:    runtime.NumCPU(),
+		chunkSize:  1 << 20,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Option defines a function setting an option of a sorting.
+type Option func(o *options)
+
+// WithThresholds sets the thresholds for switching between the
+// algorithms. The sequential threshold is at least 1, the parallel
+// one at least the sequential one. Default is DefaultThresholds().
+func WithThresholds(t Thresholds) Option {
+	return func(o *options) {
+		if t.Sequential < 1 {
+			t.Sequential = 1
+		}
+		if t.Parallel < t.Sequential {
+			t.Parallel = t.Sequential
+		}
+		o.thresholds = t
+	}
+}
+
+// WithPivot sets the strategy selecting the pivot element when
+// partitioning the data. Default is NintherPivot.
+func WithPivot(pivot PivotStrategy) Option {
+	return func(o *options) {
+		if pivot != nil {
+			o.pivot = pivot
+		}
+	}
+}
+
+// WithWorkers sets the maximum number of goroutines sorting
+// concurrently, including the calling one. It is used when
+// sorting with a context. Default is the number of CPUs.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		if n < 1 {
+			n = 1
+		}
+		o.workers = n
+	}
+}
+
+// WithChunkSize sets the number of records an external sort
+// reads, sorts, and writes as one run. Default is 1<<20.
+func WithChunkSize(n int) Option {
+	return func(o *options) {
+		if n < 1 {
+			n = 1
+		}
+		o.chunkSize = n
+	}
+}
+
+// WithTempDir sets the directory an external sort creates its
+// temporary directory for the runs in. Default is the one of
+// the operating system.
+func WithTempDir(dir string) Option {
+	return func(o *options) {
+		o.tempDir = dir
+	}
+}
+
+// EOF
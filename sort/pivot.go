@@ -0,0 +1,61 @@
+// Tideland Go Data Structures and Algorithms - Sort - Pivot
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"math/rand"
+	"sort"
+)
+
+//--------------------
+// PIVOT STRATEGIES
+//--------------------
+
+// PivotStrategy selects the pivot element for partitioning the data
+// between lo and hi, both included. It may swap elements inside of
+// this range and returns the index of the pivot.
+type PivotStrategy func(data sort.Interface, lo, hi int) int
+
+// NintherPivot selects the median of the medians of three times three
+// elements for larger ranges, otherwise the median of three. It is
+// the default strategy.
+func NintherPivot(data sort.Interface, lo, hi int) int {
+	return median(data, lo, hi)
+}
+
+// MedianOfThreePivot selects the median of the first, the middle,
+// and the last element.
+func MedianOfThreePivot(data sort.Interface, lo, hi int) int {
+	m := int(uint(lo+hi) >> 1)
+	if data.Less(m, lo) {
+		data.Swap(m, lo)
+	}
+	if data.Less(hi, m) {
+		data.Swap(hi, m)
+	}
+	if data.Less(m, lo) {
+		data.Swap(m, lo)
+	}
+	return m
+}
+
+// MiddlePivot selects the middle element.
+func MiddlePivot(data sort.Interface, lo, hi int) int {
+	return int(uint(lo+hi) >> 1)
+}
+
+// RandomPivot selects a random element.
+func RandomPivot(data sort.Interface, lo, hi int) int {
+	return lo + rand.Intn(hi-lo+1)
+}
+
+// EOF
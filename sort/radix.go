@@ -0,0 +1,351 @@
+// Tideland Go Data Structures and Algorithms - Sort - Radix
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+//--------------------
+// CONSTRAINTS
+//--------------------
+
+// Integer is the set of integer types sortable with radix sort.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Float is the set of floating-point types sortable with radix sort.
+type Float interface {
+	~float32 | ~float64
+}
+
+// ByteString is the set of byte string types sortable with radix sort.
+type ByteString interface {
+	~string | ~[]byte
+}
+
+//--------------------
+// RADIX SORT
+//--------------------
+
+// RadixInts sorts the integers in ascending order using a parallel
+// LSD radix sort.
+func RadixInts[T Integer](x []T) {
+	RadixIntsFunc(x, func(v T) T { return v })
+}
+
+// RadixIntsFunc sorts the elements by the integer keys returned by
+// the key function using a parallel LSD radix sort. The sorting is
+// stable and the key function is called once per element.
+func RadixIntsFunc[E any, K Integer](x []E, key func(e E) K) {
+	var zero K
+	signed := zero-1 < zero
+	keys := make([]uint64, len(x))
+	parallel(radixChunks(len(x)), len(x), func(_, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			k := key(x[i])
+			if signed {
+				keys[i] = uint64(int64(k)) ^ (1 << 63)
+			} else {
+				keys[i] = uint64(k)
+			}
+		}
+	})
+	lsdRadixSort(x, keys)
+}
+
+// RadixFloats sorts the floating-point numbers in ascending order using
+// a parallel LSD radix sort. The order is the total order of IEEE 754,
+// so negative NaNs are sorted first and positive NaNs last.
+func RadixFloats[T Float](x []T) {
+	RadixFloatsFunc(x, func(v T) T { return v })
+}
+
+// RadixFloatsFunc sorts the elements by the floating-point keys returned
+// by the key function like RadixFloats(). The sorting is stable and the
+// key function is called once per element.
+func RadixFloatsFunc[E any, K Float](x []E, key func(e E) K) {
+	keys := make([]uint64, len(x))
+	parallel(radixChunks(len(x)), len(x), func(_, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			bits := math.Float64bits(float64(key(x[i])))
+			if bits>>63 == 1 {
+				keys[i] = ^bits
+			} else {
+				keys[i] = bits | (1 << 63)
+			}
+		}
+	})
+	lsdRadixSort(x, keys)
+}
+
+// RadixStrings sorts the byte strings in ascending lexicographical order
+// using a parallel MSD radix sort.
+func RadixStrings[T ByteString](x []T) {
+	RadixStringsFunc(x, func(v T) T { return v })
+}
+
+// RadixStringsFunc sorts the elements by the byte string keys returned
+// by the key function using a parallel MSD radix sort. The sorting is
+// stable and the key function is called once per element.
+func RadixStringsFunc[E any, K ByteString](x []E, key func(e E) K) {
+	keys := make([]K, len(x))
+	parallel(radixChunks(len(x)), len(x), func(_, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			keys[i] = key(x[i])
+		}
+	})
+	ms := &msdRadixSort[E, K]{
+		elems:   x,
+		keys:    keys,
+		elemBuf: make([]E, len(x)),
+		keyBuf:  make([]K, len(x)),
+		sem:     make(chan struct{}, runtime.NumCPU()-1),
+	}
+	ms.sort(0, len(x), 0)
+	ms.wg.Wait()
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// msdInsertionThreshold for switching from MSD radix sort to insertion sort.
+const msdInsertionThreshold = 32
+
+// radixChunks returns the number of chunks processed concurrently.
+func radixChunks(n int) int {
+	return chunkCount(n, parallelThreshold)
+}
+
+// chunkCount returns the number of chunks for processing n elements
+// concurrently. Each chunk is larger than the parallel threshold and
+// there are not more chunks than CPUs.
+func chunkCount(n, parallel int) int {
+	chunks := n / (parallel + 1)
+	if cpus := runtime.NumCPU(); chunks > cpus {
+		chunks = cpus
+	}
+	if chunks < 1 {
+		chunks = 1
+	}
+	return chunks
+}
+
+// parallel splits the range from 0 to n into chunks and calls f
+// with the index and the range of each of them concurrently.
+func parallel(chunks, n int, f func(c, lo, hi int)) {
+	if chunks == 1 {
+		f(0, 0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(chunks)
+	for c := 0; c < chunks; c++ {
+		go func(c int) {
+			defer wg.Done()
+			f(c, c*n/chunks, (c+1)*n/chunks)
+		}(c)
+	}
+	wg.Wait()
+}
+
+// lsdRadixSort sorts the elements by their keys byte by byte starting
+// with the least significant one. Each pass counts the digits per chunk
+// and then scatters the chunks concurrently into the buffers. Passes
+// for bytes being equal in all keys are skipped.
+func lsdRadixSort[E any](elems []E, keys []uint64) {
+	n := len(elems)
+	if n < 2 {
+		return
+	}
+	chunks := radixChunks(n)
+	// Find the bits differing between the keys.
+	ors := make([]uint64, chunks)
+	ands := make([]uint64, chunks)
+	parallel(chunks, n, func(c, lo, hi int) {
+		or, and := uint64(0), ^uint64(0)
+		for _, k := range keys[lo:hi] {
+			or |= k
+			and &= k
+		}
+		ors[c] = or
+		ands[c] = and
+	})
+	or, and := uint64(0), ^uint64(0)
+	for c := 0; c < chunks; c++ {
+		or |= ors[c]
+		and &= ands[c]
+	}
+	diff := or ^ and
+	// Sort byte by byte.
+	elemBuf := make([]E, n)
+	keyBuf := make([]uint64, n)
+	srcElems, srcKeys, dstElems, dstKeys := elems, keys, elemBuf, keyBuf
+	buffered := false
+	counts := make([][256]int, chunks)
+	for shift := uint(0); shift < 64; shift += 8 {
+		if (diff>>shift)&0xff == 0 {
+			continue
+		}
+		parallel(chunks, n, func(c, lo, hi int) {
+			count := &counts[c]
+			*count = [256]int{}
+			for _, k := range srcKeys[lo:hi] {
+				count[byte(k>>shift)]++
+			}
+		})
+		pos := 0
+		for digit := 0; digit < 256; digit++ {
+			for c := 0; c < chunks; c++ {
+				count := counts[c][digit]
+				counts[c][digit] = pos
+				pos += count
+			}
+		}
+		parallel(chunks, n, func(c, lo, hi int) {
+			offsets := &counts[c]
+			for i := lo; i < hi; i++ {
+				digit := byte(srcKeys[i] >> shift)
+				dst := offsets[digit]
+				offsets[digit]++
+				dstElems[dst] = srcElems[i]
+				dstKeys[dst] = srcKeys[i]
+			}
+		})
+		srcElems, srcKeys, dstElems, dstKeys = dstElems, dstKeys, srcElems, srcKeys
+		buffered = !buffered
+	}
+	if buffered {
+		copy(elems, srcElems)
+	}
+}
+
+// msdRadixSort contains the state of a parallel MSD radix sort.
+type msdRadixSort[E any, K ByteString] struct {
+	elems   []E
+	keys    []K
+	elemBuf []E
+	keyBuf  []K
+	sem     chan struct{}
+	wg      sync.WaitGroup
+}
+
+// sort sorts the elements from lo to hi, excluded, whose keys are
+// equal up to depth. The elements are distributed into buckets by
+// the byte at depth, keys ending before are the first bucket. Large
+// buckets are sorted concurrently as long as workers are available.
+func (ms *msdRadixSort[E, K]) sort(lo, hi, depth int) {
+	for {
+		if hi-lo <= msdInsertionThreshold {
+			ms.insertionSort(lo, hi, depth)
+			return
+		}
+		var counts [257]int
+		for _, k := range ms.keys[lo:hi] {
+			counts[digitAt(k, depth)]++
+		}
+		if counts[0] == hi-lo {
+			// All keys end here.
+			return
+		}
+		if counts[digitAt(ms.keys[lo], depth)] == hi-lo {
+			// All keys share the byte, continue with the next one.
+			depth++
+			continue
+		}
+		var offsets [257]int
+		pos := lo
+		for digit, count := range counts {
+			offsets[digit] = pos
+			pos += count
+		}
+		starts := offsets
+		for i := lo; i < hi; i++ {
+			digit := digitAt(ms.keys[i], depth)
+			dst := offsets[digit]
+			offsets[digit]++
+			ms.elemBuf[dst] = ms.elems[i]
+			ms.keyBuf[dst] = ms.keys[i]
+		}
+		copy(ms.elems[lo:hi], ms.elemBuf[lo:hi])
+		copy(ms.keys[lo:hi], ms.keyBuf[lo:hi])
+		for digit := 1; digit < 257; digit++ {
+			blo, bhi := starts[digit], starts[digit]+counts[digit]
+			if bhi-blo < 2 {
+				continue
+			}
+			if bhi-blo > parallelThreshold && ms.acquire() {
+				ms.wg.Add(1)
+				go func(lo, hi int) {
+					defer ms.wg.Done()
+					defer ms.release()
+					ms.sort(lo, hi, depth+1)
+				}(blo, bhi)
+			} else {
+				ms.sort(blo, bhi, depth+1)
+			}
+		}
+		return
+	}
+}
+
+// insertionSort sorts the elements from lo to hi, excluded, whose
+// keys are equal up to depth.
+func (ms *msdRadixSort[E, K]) insertionSort(lo, hi, depth int) {
+	for i := lo + 1; i < hi; i++ {
+		for j := i; j > lo && lessFrom(ms.keys[j], ms.keys[j-1], depth); j-- {
+			ms.keys[j], ms.keys[j-1] = ms.keys[j-1], ms.keys[j]
+			ms.elems[j], ms.elems[j-1] = ms.elems[j-1], ms.elems[j]
+		}
+	}
+}
+
+// acquire tries to get a worker slot without blocking.
+func (ms *msdRadixSort[E, K]) acquire() bool {
+	select {
+	case ms.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees a worker slot.
+func (ms *msdRadixSort[E, K]) release() {
+	<-ms.sem
+}
+
+// digitAt returns the byte of the key at depth plus one,
+// or zero if the key is shorter.
+func digitAt[K ByteString](key K, depth int) int {
+	if depth < len(key) {
+		return int(key[depth]) + 1
+	}
+	return 0
+}
+
+// lessFrom compares two keys starting at depth.
+func lessFrom[K ByteString](a, b K, depth int) bool {
+	for i := depth; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// EOF
@@ -0,0 +1,189 @@
+// Tideland Go Data Structures and Algorithms - Sort - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	stdsort "sort"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/sort"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestRadixInts tests the radix sort of signed and unsigned integers.
+func TestRadixInts(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	par := sort.DefaultThresholds().Parallel
+	for _, size := range []int{0, 1, 2, 100, 10*par + 3} {
+		is := make([]int, size)
+		for i := range is {
+			is[i] = rand.Int() - rand.Int()
+		}
+		expectedInts := make([]int, size)
+		copy(expectedInts, is)
+		stdsort.Ints(expectedInts)
+
+		sort.RadixInts(is)
+		assert.Equal(is, expectedInts, fmt.Sprintf("ints with size %d", size))
+
+		us := make([]uint64, size)
+		for i := range us {
+			us[i] = rand.Uint64()
+		}
+		expectedUints := make([]uint64, size)
+		copy(expectedUints, us)
+		stdsort.Slice(expectedUints, func(i, j int) bool { return expectedUints[i] < expectedUints[j] })
+
+		sort.RadixInts(us)
+		assert.Equal(us, expectedUints, fmt.Sprintf("uints with size %d", size))
+	}
+
+	i8s := []int8{5, -128, 127, 0, -1, 1}
+	sort.RadixInts(i8s)
+	assert.Equal(i8s, []int8{-128, -1, 0, 1, 5, 127})
+}
+
+// TestRadixFloats tests the radix sort of floats.
+func TestRadixFloats(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	par := sort.DefaultThresholds().Parallel
+	for _, size := range []int{0, 1, 2, 100, 10*par + 3} {
+		fs := make([]float64, size)
+		for i := range fs {
+			fs[i] = rand.NormFloat64() * 1e6
+		}
+		expected := make([]float64, size)
+		copy(expected, fs)
+		stdsort.Float64s(expected)
+
+		sort.RadixFloats(fs)
+		assert.Equal(fs, expected, fmt.Sprintf("floats with size %d", size))
+	}
+
+	negNaN := math.Float64frombits(math.Float64bits(math.NaN()) | 1<<63)
+	fs := []float64{1, math.Inf(1), math.NaN(), -1, 0, math.Inf(-1), negNaN}
+	sort.RadixFloats(fs)
+	assert.True(math.IsNaN(fs[0]) && math.Signbit(fs[0]), "negative NaN first")
+	assert.Equal(fs[1:6], []float64{math.Inf(-1), -1, 0, 1, math.Inf(1)})
+	assert.True(math.IsNaN(fs[6]) && !math.Signbit(fs[6]), "positive NaN last")
+
+	f32s := []float32{2.5, -0.5, 1}
+	sort.RadixFloats(f32s)
+	assert.Equal(f32s, []float32{-0.5, 1, 2.5})
+}
+
+// TestRadixStrings tests the radix sort of strings and byte slices.
+func TestRadixStrings(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	par := sort.DefaultThresholds().Parallel
+	for _, size := range []int{0, 1, 2, 100, 10*par + 3} {
+		ss := make([]string, size)
+		for i := range ss {
+			ss[i] = generateString(rand.Intn(12), "abc\x00\xff")
+		}
+		expected := make([]string, size)
+		copy(expected, ss)
+		stdsort.Strings(expected)
+
+		sort.RadixStrings(ss)
+		assert.Equal(ss, expected, fmt.Sprintf("strings with size %d", size))
+	}
+
+	bs := [][]byte{[]byte("beta"), []byte("alpha"), []byte(""), []byte("alp")}
+	sort.RadixStrings(bs)
+	assert.Equal(bs, [][]byte{[]byte(""), []byte("alp"), []byte("alpha"), []byte("beta")})
+
+	prefixed := make([]string, 1000)
+	for i := range prefixed {
+		prefixed[i] = "same-long-prefix-" + generateString(3, "xyz")
+	}
+	sort.RadixStrings(prefixed)
+	assert.True(stdsort.StringsAreSorted(prefixed), "strings with common prefix")
+}
+
+// TestRadixFunc tests the radix sorts by keys and their stability.
+func TestRadixFunc(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	par := sort.DefaultThresholds().Parallel
+	rs := generateRecords(10*par+3, 100)
+	expected := make(Records, len(rs))
+	copy(expected, rs)
+	stdsort.Stable(expected)
+
+	ints := make(Records, len(rs))
+	copy(ints, rs)
+	sort.RadixIntsFunc(ints, func(r Record) int { return r.Key })
+	assert.Equal(ints, expected, "stable by integer key")
+
+	floats := make(Records, len(rs))
+	copy(floats, rs)
+	sort.RadixFloatsFunc(floats, func(r Record) float64 { return float64(r.Key) / 10 })
+	assert.Equal(floats, expected, "stable by float key")
+
+	strings := make(Records, len(rs))
+	copy(strings, rs)
+	sort.RadixStringsFunc(strings, func(r Record) string { return fmt.Sprintf("%03d", r.Key) })
+	assert.Equal(strings, expected, "stable by string key")
+}
+
+// Benchmark the standard sort of uint64s.
+func BenchmarkStandardUint64s(b *testing.B) {
+	us := generateUint64Slice(b.N)
+	stdsort.Slice(us, func(i, j int) bool { return us[i] < us[j] })
+}
+
+// Benchmark the radix sort of uint64s.
+func BenchmarkRadixUint64s(b *testing.B) {
+	us := generateUint64Slice(b.N)
+	sort.RadixInts(us)
+}
+
+// Benchmark the radix sort of strings.
+func BenchmarkRadixStrings(b *testing.B) {
+	ss := make([]string, b.N)
+	for i := range ss {
+		ss[i] = generateString(16, "0123456789abcdef")
+	}
+	sort.RadixStrings(ss)
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// generateUint64Slice generates a slice of uint64s.
+func generateUint64Slice(count int) []uint64 {
+	us := make([]uint64, count)
+	for i := range us {
+		us[i] = rand.Uint64()
+	}
+	return us
+}
+
+// generateString generates a string of the given length
+// out of the passed characters.
+func generateString(length int, chars string) string {
+	bs := make([]byte, length)
+	for i := range bs {
+		bs[i] = chars[rand.Intn(len(chars))]
+	}
+	return string(bs)
+}
+
+// EOF
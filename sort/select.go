@@ -0,0 +1,208 @@
+// Tideland Go Data Structures and Algorithms - Sort - Selection
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"sort"
+)
+
+//--------------------
+// SELECTION
+//--------------------
+
+// NthElement rearranges the data so that the element at index n is the
+// one which would be there if the data was sorted. No element before it
+// is greater and no element after it is smaller. Internally it uses
+// quickselect.
+func NthElement(data sort.Interface, n int) {
+	NewSorter().NthElement(data, n)
+}
+
+// NthElementFunc works like NthElement() on a typed slice.
+func NthElementFunc[T any](x []T, n int, less func(a, b T) bool) {
+	NthElement(&funcSlice[T]{x, less}, n)
+}
+
+// PartialSort rearranges the data so that the first k elements are the
+// smallest ones in sorted order. The order of the remaining elements is
+// unspecified.
+func PartialSort(data sort.Interface, k int) {
+	NewSorter().PartialSort(data, k)
+}
+
+// PartialSortFunc works like PartialSort() on a typed slice.
+func PartialSortFunc[T any](x []T, k int, less func(a, b T) bool) {
+	PartialSort(&funcSlice[T]{x, less}, k)
+}
+
+// TopK works like PartialSort() but selects the smallest elements of
+// large data in parallel chunks first.
+func TopK(data sort.Interface, k int) {
+	NewSorter().TopK(data, k)
+}
+
+// TopKFunc returns the smallest k elements of the typed slice in sorted
+// order without changing it. They are collected in parallel chunks using
+// heaps of size k. The order of equal elements is unspecified.
+func TopKFunc[T any](x []T, k int, less func(a, b T) bool) []T {
+	n := len(x)
+	if k > n {
+		k = n
+	}
+	if k <= 0 {
+		return []T{}
+	}
+	chunks := chunkCount(n, parallelThreshold)
+	heaps := make([][]T, chunks)
+	parallel(chunks, n, func(c, lo, hi int) {
+		h := make([]T, 0, k)
+		for _, v := range x[lo:hi] {
+			if len(h) < k {
+				h = append(h, v)
+				siftUp(h, len(h)-1, less)
+			} else if less(v, h[0]) {
+				h[0] = v
+				siftDown(h, 0, less)
+			}
+		}
+		heaps[c] = h
+	})
+	candidates := heaps[0]
+	for _, h := range heaps[1:] {
+		candidates = append(candidates, h...)
+	}
+	SliceFunc(candidates, less)
+	return candidates[:k:k]
+}
+
+// NthElement works like the package function with the
+// configuration of the sorter.
+func (s *Sorter) NthElement(data sort.Interface, n int) {
+	if n < 0 || n >= data.Len() {
+		return
+	}
+	s.selectNth(data, 0, data.Len()-1, n)
+}
+
+// PartialSort works like the package function with the
+// configuration of the sorter.
+func (s *Sorter) PartialSort(data sort.Interface, k int) {
+	n := data.Len()
+	if k > n {
+		k = n
+	}
+	if k <= 0 {
+		return
+	}
+	if k < n {
+		s.selectNth(data, 0, n-1, k-1)
+	}
+	s.sortRange(data, 0, k-1)
+}
+
+// TopK works like the package function with the
+// configuration of the sorter.
+func (s *Sorter) TopK(data sort.Interface, k int) {
+	n := data.Len()
+	if k > n {
+		k = n
+	}
+	if k <= 0 {
+		return
+	}
+	chunks := chunkCount(n, s.opts.thresholds.Parallel)
+	for chunks > 1 && n/chunks < k {
+		chunks--
+	}
+	if chunks > 1 {
+		// Select the candidates per chunk and move them to the front.
+		parallel(chunks, n, func(c, lo, hi int) {
+			s.selectNth(data, lo, hi-1, lo+k-1)
+		})
+		for c := 1; c < chunks; c++ {
+			lo := c * n / chunks
+			for i := 0; i < k; i++ {
+				data.Swap(c*k+i, lo+i)
+			}
+		}
+		n = chunks * k
+	}
+	if k < n {
+		s.selectNth(data, 0, n-1, k-1)
+	}
+	s.sortRange(data, 0, k-1)
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// selectNth partitions the data between lo and hi until the
+// element at n is at its sorted position.
+func (s *Sorter) selectNth(data sort.Interface, lo, hi, n int) {
+	for hi-lo > s.opts.thresholds.Sequential {
+		plo, phi := partition(data, lo, hi, s.opts.pivot)
+		switch {
+		case n <= plo:
+			hi = plo
+		case n >= phi:
+			lo = phi
+		default:
+			// Element at n is the pivot.
+			return
+		}
+	}
+	insertionSort(data, lo, hi)
+}
+
+// sortRange sorts the data between lo and hi using
+// the parallel quicksort.
+func (s *Sorter) sortRange(data sort.Interface, lo, hi int) {
+	done := make(chan bool)
+
+	go s.parallelQuickSort(data, lo, hi, done)
+
+	<-done
+}
+
+// siftUp moves the element at i up in the max-heap h.
+func siftUp[T any](h []T, i int, less func(a, b T) bool) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !less(h[parent], h[i]) {
+			return
+		}
+		h[parent], h[i] = h[i], h[parent]
+		i = parent
+	}
+}
+
+// siftDown moves the element at i down in the max-heap h.
+func siftDown[T any](h []T, i int, less func(a, b T) bool) {
+	for {
+		largest := i
+		left, right := 2*i+1, 2*i+2
+		if left < len(h) && less(h[largest], h[left]) {
+			largest = left
+		}
+		if right < len(h) && less(h[largest], h[right]) {
+			largest = right
+		}
+		if largest == i {
+			return
+		}
+		h[i], h[largest] = h[largest], h[i]
+		i = largest
+	}
+}
+
+// EOF
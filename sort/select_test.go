@@ -0,0 +1,160 @@
+// Tideland Go Data Structures and Algorithms - Sort - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math/rand"
+	stdsort "sort"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/sort"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestNthElement tests the selection of the nth element.
+func TestNthElement(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	for _, size := range []int{1, 2, 10, 1000, 100000} {
+		for _, n := range []int{0, size / 3, size - 1} {
+			is := generateIntSlice(size)
+			expected := make([]int, size)
+			copy(expected, is)
+			stdsort.Ints(expected)
+			info := fmt.Sprintf("element %d of %d", n, size)
+
+			sort.NthElement(is, n)
+			assert.Equal(is[n], expected[n], info)
+			for i := 0; i < n; i++ {
+				assert.True(is[i] <= is[n], info)
+			}
+			for i := n + 1; i < size; i++ {
+				assert.True(is[i] >= is[n], info)
+			}
+		}
+	}
+
+	fs := []float64{3, 1, 2}
+	sort.NthElementFunc(fs, 1, func(a, b float64) bool { return a < b })
+	assert.Equal(fs[1], 2.0)
+	sort.NthElementFunc(fs, 3, func(a, b float64) bool { return a < b })
+}
+
+// TestPartialSort tests sorting only the smallest elements.
+func TestPartialSort(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	for _, size := range []int{0, 1, 10, 1000, 5000} {
+		for _, k := range []int{0, 1, 10, size, size + 5} {
+			is := generateIntSlice(size)
+			expected := make([]int, size)
+			copy(expected, is)
+			stdsort.Ints(expected)
+			top := min(max(k, 0), size)
+			info := fmt.Sprintf("%d of %d", k, size)
+
+			sort.PartialSort(is, k)
+			assert.Equal([]int(is[:top]), expected[:top], info)
+			stdsort.Ints(is)
+			assert.Equal([]int(is), expected, "permutation "+info)
+
+			ps := generatePersons(size)
+			expectedPersons := make([]Person, size)
+			copy(expectedPersons, ps)
+			stdsort.Slice(expectedPersons, func(i, j int) bool { return expectedPersons[i].Age < expectedPersons[j].Age })
+
+			sort.PartialSortFunc(ps, k, func(a, b Person) bool { return a.Age < b.Age })
+			assert.Equal(ages(ps[:top]), ages(expectedPersons[:top]), info)
+		}
+	}
+}
+
+// TestTopK tests the parallel selection of the smallest elements.
+func TestTopK(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	par := sort.DefaultThresholds().Parallel
+	for _, size := range []int{0, 1, 1000, 10*par + 3} {
+		for _, k := range []int{0, 1, 100, par + 1, size, size + 5} {
+			is := generateIntSlice(size)
+			original := make([]int, size)
+			copy(original, is)
+			expected := make([]int, size)
+			copy(expected, is)
+			stdsort.Ints(expected)
+			top := min(max(k, 0), size)
+			info := fmt.Sprintf("%d of %d", k, size)
+
+			sort.TopK(is, k)
+			assert.Equal([]int(is[:top]), expected[:top], info)
+			stdsort.Ints(is)
+			assert.Equal([]int(is), expected, "permutation "+info)
+
+			less := func(a, b int) bool { return a < b }
+			topK := sort.TopKFunc(original, k, less)
+			assert.Equal(topK, expected[:top], "func "+info)
+			assert.False(stdsort.IntsAreSorted(original) && size > 1, "input is unchanged")
+		}
+	}
+
+	small := sort.NewSorter(sort.WithThresholds(sort.Thresholds{Sequential: 4, Parallel: 64}))
+	is := generateIntSlice(10000)
+	expected := make([]int, len(is))
+	copy(expected, is)
+	stdsort.Ints(expected)
+	small.TopK(is, 50)
+	assert.Equal([]int(is[:50]), expected[:50], "many chunks")
+}
+
+// Benchmark the selection of the smallest elements by sorting.
+func BenchmarkTopKSort(b *testing.B) {
+	is := generateIntSlice(b.N)
+	sort.Sort(is)
+}
+
+// Benchmark the parallel selection of the smallest elements.
+func BenchmarkTopK(b *testing.B) {
+	is := generateIntSlice(b.N)
+	sort.TopK(is, 100)
+}
+
+// Benchmark the heap based selection of the smallest elements.
+func BenchmarkTopKFunc(b *testing.B) {
+	is := generateIntSlice(b.N)
+	sort.TopKFunc(is, 100, func(a, b int) bool { return a < b })
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// generatePersons generates persons with random ages.
+func generatePersons(count int) []Person {
+	ps := make([]Person, count)
+	for i := range ps {
+		ps[i] = Person{Name: fmt.Sprintf("p%d", i), Age: rand.Intn(100)}
+	}
+	return ps
+}
+
+// ages returns the ages of the persons.
+func ages(ps []Person) []int {
+	as := make([]int, len(ps))
+	for i, p := range ps {
+		as[i] = p.Age
+	}
+	return as
+}
+
+// EOF
@@ -0,0 +1,76 @@
+// Tideland Go Data Structures and Algorithms - Sort - Slices
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"cmp"
+	"reflect"
+)
+
+//--------------------
+// SLICE SORTING
+//--------------------
+
+// Slice sorts the slice x using the less function like the
+// standard sort.Slice(). It panics if x is no slice. Internally
+// it uses the parallel quicksort.
+func Slice(x any, less func(i, j int) bool) {
+	rv := reflect.ValueOf(x)
+	Sort(&lessSwap{
+		length: rv.Len(),
+		less:   less,
+		swap:   reflect.Swapper(x),
+	})
+}
+
+// SliceFunc sorts the slice x of any type using the less function
+// comparing two values. Internally it uses the parallel quicksort.
+func SliceFunc[T any](x []T, less func(a, b T) bool) {
+	Sort(&funcSlice[T]{
+		values: x,
+		less:   less,
+	})
+}
+
+// Ordered sorts the slice x of an ordered type in ascending order.
+// Like cmp.Less() NaNs are ordered before other values. Internally
+// it uses the parallel quicksort.
+func Ordered[T cmp.Ordered](x []T) {
+	SliceFunc(x, cmp.Less[T])
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// lessSwap implements sort.Interface for any slice.
+type lessSwap struct {
+	length int
+	less   func(i, j int) bool
+	swap   func(i, j int)
+}
+
+func (ls *lessSwap) Len() int           { return ls.length }
+func (ls *lessSwap) Less(i, j int) bool { return ls.less(i, j) }
+func (ls *lessSwap) Swap(i, j int)      { ls.swap(i, j) }
+
+// funcSlice implements sort.Interface for typed slices.
+type funcSlice[T any] struct {
+	values []T
+	less   func(a, b T) bool
+}
+
+func (fs *funcSlice[T]) Len() int           { return len(fs.values) }
+func (fs *funcSlice[T]) Less(i, j int) bool { return fs.less(fs.values[i], fs.values[j]) }
+func (fs *funcSlice[T]) Swap(i, j int)      { fs.values[i], fs.values[j] = fs.values[j], fs.values[i] }
+
+// EOF
@@ -0,0 +1,111 @@
+// Tideland Go Data Structures and Algorithms - Sort - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"math"
+	"math/rand"
+	stdsort "sort"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/sort"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestSlice tests sorting any slice with a less function.
+func TestSlice(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	for _, count := range []int{0, 1, 10, 1000, 100000} {
+		is := generateIntSlice(count)
+		expected := make([]int, count)
+		copy(expected, is)
+		stdsort.Ints(expected)
+
+		sort.Slice(is, func(i, j int) bool { return is[i] < is[j] })
+		assert.Equal([]int(is), expected, "sorted like the standard sort")
+	}
+
+	people := []Person{{"Alice", 30}, {"Bob", 25}, {"Carol", 35}}
+	sort.Slice(people, func(i, j int) bool { return people[i].Age < people[j].Age })
+	assert.Equal(people, []Person{{"Bob", 25}, {"Alice", 30}, {"Carol", 35}})
+}
+
+// TestSliceFunc tests sorting a typed slice with a less function.
+func TestSliceFunc(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	for _, count := range []int{0, 1, 10, 1000, 100000} {
+		ps := make([]Person, count)
+		for i := range ps {
+			ps[i] = Person{Name: "p", Age: rand.Intn(1000000)}
+		}
+		byAge := func(a, b Person) bool { return a.Age < b.Age }
+
+		sort.SliceFunc(ps, byAge)
+		assert.True(stdsort.SliceIsSorted(ps, func(i, j int) bool { return byAge(ps[i], ps[j]) }), "sorted")
+	}
+}
+
+// TestOrdered tests sorting slices of ordered types.
+func TestOrdered(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	fs := make([]float64, 100000)
+	for i := range fs {
+		fs[i] = rand.NormFloat64()
+	}
+	fs[17] = math.NaN()
+	fs[4711] = math.Inf(-1)
+	expected := make([]float64, len(fs))
+	copy(expected, fs)
+	stdsort.Float64s(expected)
+
+	sort.Ordered(fs)
+	assert.True(math.IsNaN(fs[0]), "NaN is first")
+	assert.Equal(fs[1:], expected[1:], "sorted like the standard sort")
+
+	ss := []string{"delta", "alpha", "charlie", "bravo"}
+	sort.Ordered(ss)
+	assert.Equal(ss, []string{"alpha", "bravo", "charlie", "delta"})
+}
+
+// Benchmark the standard slice sort.
+func BenchmarkStandardSlice(b *testing.B) {
+	is := generateIntSlice(b.N)
+	stdsort.Slice(is, func(i, j int) bool { return is[i] < is[j] })
+}
+
+// Benchmark the parallel slice sort.
+func BenchmarkSlice(b *testing.B) {
+	is := generateIntSlice(b.N)
+	sort.Slice(is, func(i, j int) bool { return is[i] < is[j] })
+}
+
+// Benchmark the parallel ordered sort.
+func BenchmarkOrdered(b *testing.B) {
+	is := generateIntSlice(b.N)
+	sort.Ordered([]int(is))
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// Person is used for sorting structs.
+type Person struct {
+	Name string
+	Age  int
+}
+
+// EOF
@@ -26,6 +26,23 @@ var sequentialThreshold = runtime.NumCPU()*4 - 1
 // parallelThreshold for switching from parallel to sequential quick sort.
 var parallelThreshold = runtime.NumCPU()*2048 - 1
 
+// Thresholds contains the sizes of data ranges for switching between
+// the algorithms. Ranges not larger than Sequential are sorted with
+// insertion sort, those not larger than Parallel sequentially.
+type Thresholds struct {
+	Sequential int
+	Parallel   int
+}
+
+// DefaultThresholds returns the thresholds used by default. They
+// are based on the number of CPUs.
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		Sequential: sequentialThreshold,
+		Parallel:   parallelThreshold,
+	}
+}
+
 //--------------------
 // HELPING FUNCS
 //--------------------
@@ -66,9 +83,13 @@ func median(data sort.Interface, lo, hi int) int {
 	return m
 }
 
-// partition the data based on the median.
-func partition(data sort.Interface, lo, hi int) (int, int) {
-	med := median(data, lo, hi)
+// partition the data based on the pivot selected by the strategy. It
+// returns the end of the lower and the start of the upper part. In case
+// of a small lower part the elements equal to the pivot are moved next
+// to it and excluded from the upper part. So many duplicates don't lead
+// to quadratic runtime.
+func partition(data sort.Interface, lo, hi int, pivot PivotStrategy) (int, int) {
+	med := pivot(data, lo, hi)
 	idx := lo
 	data.Swap(med, hi)
 	for i := lo; i < hi; i++ {
@@ -78,16 +99,25 @@ func partition(data sort.Interface, lo, hi int) (int, int) {
 		}
 	}
 	data.Swap(idx, hi)
-	return idx - 1, idx + 1
+	eq := idx + 1
+	if idx-lo < (hi-lo)/8 {
+		for i := eq; i <= hi; i++ {
+			if !data.Less(idx, i) {
+				data.Swap(i, eq)
+				eq++
+			}
+		}
+	}
+	return idx - 1, eq
 }
 
 // sequentialQuickSort using itself recursively.
-func sequentialQuickSort(data sort.Interface, lo, hi int) {
-	if hi-lo > sequentialThreshold {
+func (s *Sorter) sequentialQuickSort(data sort.Interface, lo, hi int) {
+	if hi-lo > s.opts.thresholds.Sequential {
 		// Use sequential quicksort.
-		plo, phi := partition(data, lo, hi)
-		sequentialQuickSort(data, lo, plo)
-		sequentialQuickSort(data, phi, hi)
+		plo, phi := partition(data, lo, hi, s.opts.pivot)
+		s.sequentialQuickSort(data, lo, plo)
+		s.sequentialQuickSort(data, phi, hi)
 	} else {
 		// Use insertion sort.
 		insertionSort(data, lo, hi)
@@ -95,19 +125,19 @@ func sequentialQuickSort(data sort.Interface, lo, hi int) {
 }
 
 // parallelQuickSort using itself recursively and concurrent.
-func parallelQuickSort(data sort.Interface, lo, hi int, done chan bool) {
-	if hi-lo > parallelThreshold {
+func (s *Sorter) parallelQuickSort(data sort.Interface, lo, hi int, done chan bool) {
+	if hi-lo > s.opts.thresholds.Parallel {
 		// Parallel QuickSort.
-		plo, phi := partition(data, lo, hi)
+		plo, phi := partition(data, lo, hi, s.opts.pivot)
 		partDone := make(chan bool)
-		go parallelQuickSort(data, lo, plo, partDone)
-		go parallelQuickSort(data, phi, hi, partDone)
+		go s.parallelQuickSort(data, lo, plo, partDone)
+		go s.parallelQuickSort(data, phi, hi, partDone)
 		// Wait for the end of both sorts.
 		<-partDone
 		<-partDone
 	} else {
 		// Sequential QuickSort.
-		sequentialQuickSort(data, lo, hi)
+		s.sequentialQuickSort(data, lo, hi)
 	}
 	// Signal that it's done.
 	done <- true
@@ -121,11 +151,7 @@ func parallelQuickSort(data sort.Interface, lo, hi int, done chan bool) {
 // to the standard sort interface. Internally it uses the
 // parallel quicksort.
 func Sort(data sort.Interface) {
-	done := make(chan bool)
-
-	go parallelQuickSort(data, 0, data.Len()-1, done)
-
-	<-done
+	NewSorter().Sort(data)
 }
 
 // EOF
@@ -37,6 +37,49 @@ func TestPivot(t *testing.T) {
 	assert.Equal(td, ByteSlice{11, 13, 15, 6, 17, 20, 21, 94, 23, 47, 59, 88, 78, 67, 51}, "Prepared data.")
 }
 
+// Test pivot with many duplicates.
+func TestPivotDuplicates(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	// All elements equal the pivot, so the upper part is empty.
+	td := make(ByteSlice, 100)
+	plh, puh := sort.Partition(td, 0, len(td)-1)
+	assert.Equal(plh, -1, "Pivot lower half.")
+	assert.Equal(puh, 100, "Pivot upper half.")
+	// Mostly equal values with an empty lower part.
+	td = make(ByteSlice, 100)
+	for i := range td {
+		td[i] = 1
+		if i%10 == 0 {
+			td[i] = 2
+		}
+	}
+	plh, puh = sort.Partition(td, 0, len(td)-1)
+	for i := 0; i <= plh; i++ {
+		assert.True(td[i] < td[plh+1], "Lower half is less than the pivot.")
+	}
+	for i := plh + 1; i < puh; i++ {
+		assert.Equal(td[i], td[plh+1], "Middle equals the pivot.")
+	}
+	for i := puh; i < len(td); i++ {
+		assert.True(td[i] > td[plh+1], "Upper half is greater than the pivot.")
+	}
+	assert.Equal(puh, 90, "Duplicates of the pivot are excluded.")
+}
+
+// Test sorting many equal values with a bounded number of comparisons.
+func TestSortDuplicates(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	n := 20000
+	cs := &CountingSlice{IntSlice: make(stdsort.IntSlice, n)}
+	for i := range cs.IntSlice {
+		cs.IntSlice[i] = i % 3
+	}
+	sort.NewSorter().Sort(cs)
+	assert.True(stdsort.IsSorted(cs.IntSlice), "Data is sorted.")
+	// Quadratic runtime would need far more than 10 * n * log(n).
+	assert.True(cs.calls.Load() < int64(10*n*15), "Comparisons are bounded.")
+}
+
 // Benchmark the standart integer sort.
 func BenchmarkStandardSort(b *testing.B) {
 	is := generateIntSlice(b.N)
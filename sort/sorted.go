@@ -0,0 +1,232 @@
+// Tideland Go Data Structures and Algorithms - Sort - Sorted Data
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"sort"
+	"sync"
+)
+
+//--------------------
+// SEARCHING
+//--------------------
+
+// LowerBound returns the index of the first element of the sorted data
+// which is not below a searched value. The function below reports if
+// the element at index i is below it. If there's no such element the
+// length of the data is returned.
+func LowerBound(data sort.Interface, below func(i int) bool) int {
+	return sort.Search(data.Len(), func(i int) bool {
+		return !below(i)
+	})
+}
+
+// UpperBound returns the index of the first element of the sorted data
+// which is above a searched value. The function above reports if the
+// element at index i is above it. If there's no such element the length
+// of the data is returned.
+func UpperBound(data sort.Interface, above func(i int) bool) int {
+	return sort.Search(data.Len(), above)
+}
+
+// LowerBoundFunc returns the index of the first element of the sorted
+// slice which is not less than the value.
+func LowerBoundFunc[T any](x []T, value T, less func(a, b T) bool) int {
+	return sort.Search(len(x), func(i int) bool {
+		return !less(x[i], value)
+	})
+}
+
+// UpperBoundFunc returns the index of the first element of the sorted
+// slice which is greater than the value.
+func UpperBoundFunc[T any](x []T, value T, less func(a, b T) bool) int {
+	return sort.Search(len(x), func(i int) bool {
+		return less(value, x[i])
+	})
+}
+
+//--------------------
+// DEDUPLICATION
+//--------------------
+
+// Unique moves the first of each group of equal elements of the sorted
+// data to the front, keeping their order. It returns their number. The
+// duplicates follow in unspecified order.
+func Unique(data sort.Interface) int {
+	n := data.Len()
+	if n < 2 {
+		return n
+	}
+	w := 1
+	for r := 1; r < n; r++ {
+		if data.Less(w-1, r) {
+			if w != r {
+				data.Swap(w, r)
+			}
+			w++
+		}
+	}
+	return w
+}
+
+// UniqueFunc removes the duplicates from the sorted slice in place
+// and returns the shortened slice.
+func UniqueFunc[T any](x []T, less func(a, b T) bool) []T {
+	if len(x) < 2 {
+		return x
+	}
+	w := 1
+	for r := 1; r < len(x); r++ {
+		if less(x[w-1], x[r]) {
+			x[w] = x[r]
+			w++
+		}
+	}
+	return x[:w]
+}
+
+//--------------------
+// MERGING
+//--------------------
+
+// Merge passes the elements of the two sorted collections a and b in
+// sorted order to emit, each one with its collection and its index. As
+// a sort.Interface only compares its own elements, less reports if the
+// element i of b is less than the element j of a. Equal elements of a
+// are emitted before those of b.
+func Merge(a, b sort.Interface, less func(i, j int) bool, emit func(data sort.Interface, i int)) {
+	na, nb := a.Len(), b.Len()
+	i, j := 0, 0
+	for i < na && j < nb {
+		if less(j, i) {
+			emit(b, j)
+			j++
+		} else {
+			emit(a, i)
+			i++
+		}
+	}
+	for ; i < na; i++ {
+		emit(a, i)
+	}
+	for ; j < nb; j++ {
+		emit(b, j)
+	}
+}
+
+// MergeRuns merges the sorted runs of the data before and starting at
+// mid in place. It needs no destination and no comparison between two
+// collections like Merge(). Equal elements keep their order. Large data
+// is merged in parallel.
+func MergeRuns(data sort.Interface, mid int) {
+	n := data.Len()
+	if mid <= 0 || mid >= n {
+		return
+	}
+	symMerge(data, 0, mid, n, parallelThreshold)
+}
+
+// MergeK merges the sorted runs of the data in place. The first run
+// starts at 0, the following ones at the passed starts in ascending
+// order. Pairs of runs are merged in parallel until one is left. Equal
+// elements keep their order.
+func MergeK(data sort.Interface, starts []int) {
+	n := data.Len()
+	bounds := []int{0}
+	for _, start := range starts {
+		if start > bounds[len(bounds)-1] && start < n {
+			bounds = append(bounds, start)
+		}
+	}
+	bounds = append(bounds, n)
+	for len(bounds) > 2 {
+		var wg sync.WaitGroup
+		merged := []int{0}
+		for i := 0; i+2 < len(bounds); i += 2 {
+			wg.Add(1)
+			go func(a, m, b int) {
+				defer wg.Done()
+				symMerge(data, a, m, b, parallelThreshold)
+			}(bounds[i], bounds[i+1], bounds[i+2])
+			merged = append(merged, bounds[i+2])
+		}
+		if len(bounds)%2 == 0 {
+			// Odd number of runs, the last one is kept.
+			merged = append(merged, n)
+		}
+		wg.Wait()
+		bounds = merged
+	}
+}
+
+// MergeFunc returns a new slice containing the elements of the two
+// sorted slices in sorted order. Equal elements of a are placed before
+// those of b.
+func MergeFunc[T any](a, b []T, less func(a, b T) bool) []T {
+	merged := make([]T, len(a)+len(b))
+	mergeInto(merged, a, b, less)
+	return merged
+}
+
+// MergeKFunc returns a new slice containing the elements of all sorted
+// slices in sorted order. Pairs of slices are merged in parallel until
+// one is left. Equal elements keep the order of their slices.
+func MergeKFunc[T any](xs [][]T, less func(a, b T) bool) []T {
+	if len(xs) == 0 {
+		return []T{}
+	}
+	if len(xs) == 1 {
+		merged := make([]T, len(xs[0]))
+		copy(merged, xs[0])
+		return merged
+	}
+	for len(xs) > 1 {
+		var wg sync.WaitGroup
+		merged := make([][]T, (len(xs)+1)/2)
+		for i := 0; i+1 < len(xs); i += 2 {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				merged[i/2] = MergeFunc(xs[i], xs[i+1], less)
+			}(i)
+		}
+		if len(xs)%2 == 1 {
+			merged[len(merged)-1] = xs[len(xs)-1]
+		}
+		wg.Wait()
+		xs = merged
+	}
+	return xs[0]
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// mergeInto merges the sorted slices a and b into dst.
+func mergeInto[T any](dst, a, b []T, less func(a, b T) bool) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if less(b[j], a[i]) {
+			dst[k] = b[j]
+			j++
+		} else {
+			dst[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], a[i:])
+	copy(dst[k:], b[j:])
+}
+
+// EOF
@@ -0,0 +1,184 @@
+// Tideland Go Data Structures and Algorithms - Sort - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math/rand"
+	stdsort "sort"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/sort"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestBounds tests the lower and upper bound search.
+func TestBounds(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	is := stdsort.IntSlice{1, 2, 2, 2, 5, 7}
+	less := func(a, b int) bool { return a < b }
+	tests := []struct {
+		value int
+		lower int
+		upper int
+	}{
+		{0, 0, 0},
+		{1, 0, 1},
+		{2, 1, 4},
+		{3, 4, 4},
+		{7, 5, 6},
+		{8, 6, 6},
+	}
+	for _, test := range tests {
+		info := fmt.Sprintf("value %d", test.value)
+		lower := sort.LowerBound(is, func(i int) bool { return is[i] < test.value })
+		upper := sort.UpperBound(is, func(i int) bool { return is[i] > test.value })
+		assert.Equal(lower, test.lower, info)
+		assert.Equal(upper, test.upper, info)
+		assert.Equal(sort.LowerBoundFunc(is, test.value, less), test.lower, info)
+		assert.Equal(sort.UpperBoundFunc(is, test.value, less), test.upper, info)
+	}
+}
+
+// TestUnique tests the removal of duplicates.
+func TestUnique(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	less := func(a, b int) bool { return a < b }
+	tests := []struct {
+		in  []int
+		out []int
+	}{
+		{[]int{}, []int{}},
+		{[]int{1}, []int{1}},
+		{[]int{1, 1, 1}, []int{1}},
+		{[]int{1, 2, 3}, []int{1, 2, 3}},
+		{[]int{1, 1, 2, 3, 3, 3, 4, 5, 5}, []int{1, 2, 3, 4, 5}},
+	}
+	for _, test := range tests {
+		is := make(stdsort.IntSlice, len(test.in))
+		copy(is, test.in)
+		n := sort.Unique(is)
+		assert.Equal([]int(is[:n]), test.out)
+		stdsort.Ints(is)
+		assert.Equal([]int(is), test.in, "duplicates are kept at the end")
+
+		is = make(stdsort.IntSlice, len(test.in))
+		copy(is, test.in)
+		assert.Equal(sort.UniqueFunc([]int(is), less), test.out)
+	}
+
+	rs := Records{{1, 0}, {1, 1}, {2, 2}, {2, 3}, {3, 4}}
+	n := sort.Unique(rs)
+	assert.Equal(rs[:n], Records{{1, 0}, {2, 2}, {3, 4}}, "first of equal elements is kept")
+}
+
+// TestMerge tests merging two sorted collections.
+func TestMerge(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	for _, sizes := range [][2]int{{0, 0}, {0, 5}, {5, 0}, {100, 37}} {
+		ra := generateRecords(sizes[0], 20)
+		rb := generateRecords(sizes[1], 20)
+		stdsort.Stable(ra)
+		stdsort.Stable(rb)
+		expected := append(append(Records{}, ra...), rb...)
+		stdsort.Stable(expected)
+		merged := Records{}
+
+		sort.Merge(ra, rb, func(i, j int) bool {
+			return rb[i].Key < ra[j].Key
+		}, func(data stdsort.Interface, i int) {
+			merged = append(merged, data.(Records)[i])
+		})
+		assert.Equal(merged, expected, fmt.Sprintf("sizes %d and %d", sizes[0], sizes[1]))
+	}
+
+	a := []int{1, 3, 5, 7}
+	b := []int{2, 3, 4, 8, 9}
+	merged := sort.MergeFunc(a, b, func(a, b int) bool { return a < b })
+	assert.Equal(merged, []int{1, 2, 3, 3, 4, 5, 7, 8, 9})
+}
+
+// TestMergeRuns tests merging two sorted runs of data.
+func TestMergeRuns(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	par := sort.DefaultThresholds().Parallel
+	for _, size := range []int{0, 1, 10, 3*par + 5} {
+		for _, mid := range []int{0, size / 3, size} {
+			rs := generateRecords(size, 50)
+			stdsort.Stable(rs[:mid])
+			stdsort.Stable(rs[mid:])
+			expected := make(Records, size)
+			copy(expected, rs)
+			stdsort.Stable(expected)
+
+			sort.MergeRuns(rs, mid)
+			assert.Equal(rs, expected, fmt.Sprintf("size %d with mid %d", size, mid))
+		}
+	}
+}
+
+// TestMergeK tests merging multiple sorted runs.
+func TestMergeK(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	par := sort.DefaultThresholds().Parallel
+	for _, runs := range []int{1, 2, 3, 7, 16} {
+		size := runs * (par/2 + 3)
+		rs := generateRecords(size, 100)
+		var starts []int
+		for i := 1; i < runs; i++ {
+			starts = append(starts, i*size/runs)
+		}
+		bounds := append(append([]int{0}, starts...), size)
+		xs := make([][]Record, runs)
+		for i := 0; i < runs; i++ {
+			stdsort.Stable(rs[bounds[i]:bounds[i+1]])
+			xs[i] = append([]Record(nil), rs[bounds[i]:bounds[i+1]]...)
+		}
+		expected := make(Records, size)
+		copy(expected, rs)
+		stdsort.Stable(expected)
+		info := fmt.Sprintf("%d runs", runs)
+
+		sort.MergeK(rs, starts)
+		assert.Equal(rs, expected, info)
+
+		merged := sort.MergeKFunc(xs, func(a, b Record) bool { return a.Key < b.Key })
+		assert.Equal(Records(merged), expected, "func "+info)
+	}
+
+	assert.Empty(sort.MergeKFunc(nil, func(a, b int) bool { return a < b }))
+	is := stdsort.IntSlice{3, 1, 2}
+	sort.MergeK(is, []int{0, 3, 5})
+	assert.Equal(is, stdsort.IntSlice{3, 1, 2}, "invalid starts are ignored")
+	sort.MergeK(is, []int{1, 1, 2})
+	assert.Equal(is, stdsort.IntSlice{1, 2, 3})
+}
+
+// Benchmark the parallel k-way merge.
+func BenchmarkMergeKFunc(b *testing.B) {
+	xs := make([][]int, 16)
+	for i := range xs {
+		xs[i] = make([]int, b.N/16+1)
+		for j := range xs[i] {
+			xs[i][j] = rand.Int()
+		}
+		stdsort.Ints(xs[i])
+	}
+	b.ResetTimer()
+	sort.MergeKFunc(xs, func(a, b int) bool { return a < b })
+}
+
+// EOF
@@ -0,0 +1,138 @@
+// Tideland Go Data Structures and Algorithms - Sort - Sorter
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+//--------------------
+// SORTER
+//--------------------
+
+// Sorter sorts data with a configuration set by options. So the
+// thresholds and the pivot strategy can be tuned per usage.
+type Sorter struct {
+	opts *options
+}
+
+// NewSorter creates a sorter with the passed options.
+func NewSorter(options ...Option) *Sorter {
+	return &Sorter{
+		opts: newOptions(options),
+	}
+}
+
+// Sort sorts the data using the parallel quicksort.
+func (s *Sorter) Sort(data sort.Interface) {
+	s.sortRange(data, 0, data.Len()-1)
+}
+
+// SortContext sorts the data using the parallel quicksort with the
+// configured number of workers. It stops when the context is cancelled
+// and returns its error.
+func (s *Sorter) SortContext(ctx context.Context, data sort.Interface) error {
+	qs := &contextQuickSort{
+		opts: s.opts,
+		done: ctx.Done(),
+		data: data,
+		sem:  make(chan struct{}, s.opts.workers-1),
+	}
+	qs.sort(0, data.Len()-1)
+	qs.wg.Wait()
+	if qs.interrupted.Load() {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Stable sorts the data using the parallel merge sort while keeping
+// the order of equal elements.
+func (s *Sorter) Stable(data sort.Interface) {
+	done := make(chan bool)
+
+	go s.parallelMergeSort(data, 0, data.Len()-1, done)
+
+	<-done
+}
+
+//--------------------
+// CALIBRATION
+//--------------------
+
+// Candidates for the calibration of the thresholds.
+var (
+	sequentialCandidates = []int{4, 8, 12, 16, 24, 32, 48, 64}
+	parallelCandidates   = []int{1 << 10, 1 << 11, 1 << 12, 1 << 13, 1 << 14, 1 << 15, 1 << 16, 1 << 17, 1 << 18}
+)
+
+// Calibrate measures the sorting of random sample data of the given
+// size with different thresholds on the current machine and returns
+// the fastest ones. First the sequential threshold is chosen, then the
+// parallel one. Only parallel thresholds below the sample size are
+// tried, so it should be the size of the typically sorted data. In
+// case of a size lower than 1 it is 1<<18.
+func Calibrate(sampleSize int) Thresholds {
+	if sampleSize < 1 {
+		sampleSize = 1 << 18
+	}
+	sample := make(sort.IntSlice, sampleSize)
+	for i := range sample {
+		sample[i] = rand.Int()
+	}
+	data := make(sort.IntSlice, sampleSize)
+	measure := func(t Thresholds, sequential bool) time.Duration {
+		s := NewSorter(WithThresholds(t))
+		fastest := time.Duration(0)
+		for i := 0; i < 3; i++ {
+			copy(data, sample)
+			start := time.Now()
+			if sequential {
+				s.sequentialQuickSort(data, 0, len(data)-1)
+			} else {
+				s.Sort(data)
+			}
+			if d := time.Since(start); i == 0 || d < fastest {
+				fastest = d
+			}
+		}
+		return fastest
+	}
+
+	best := DefaultThresholds()
+	fastest := time.Duration(0)
+	for i, candidate := range sequentialCandidates {
+		d := measure(Thresholds{candidate, candidate}, true)
+		if i == 0 || d < fastest {
+			best.Sequential = candidate
+			fastest = d
+		}
+	}
+	best.Parallel = sampleSize
+	fastest = measure(best, false)
+	for _, candidate := range parallelCandidates {
+		if candidate >= sampleSize {
+			break
+		}
+		d := measure(Thresholds{best.Sequential, candidate}, false)
+		if d < fastest {
+			best.Parallel = candidate
+			fastest = d
+		}
+	}
+	return best
+}
+
+// EOF
@@ -0,0 +1,121 @@
+// Tideland Go Data Structures and Algorithms - Sort - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"fmt"
+	stdsort "sort"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/sort"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestSorterThresholds tests sorting with different thresholds.
+func TestSorterThresholds(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	thresholds := []sort.Thresholds{
+		{Sequential: 0, Parallel: 0},
+		{Sequential: 1, Parallel: 1},
+		{Sequential: 8, Parallel: 256},
+		{Sequential: 64, Parallel: 32},
+		{Sequential: 16, Parallel: 1 << 20},
+	}
+	for _, th := range thresholds {
+		s := sort.NewSorter(sort.WithThresholds(th))
+		info := fmt.Sprintf("thresholds %+v", th)
+
+		is := generateIntSlice(2000)
+		s.Sort(is)
+		assert.True(stdsort.IsSorted(is), info)
+
+		is = generateIntSlice(2000)
+		assert.Nil(s.SortContext(context.Background(), is))
+		assert.True(stdsort.IsSorted(is), info)
+
+		rs := generateRecords(2000, 100)
+		expected := make(Records, len(rs))
+		copy(expected, rs)
+		stdsort.Stable(expected)
+		s.Stable(rs)
+		assert.Equal(rs, expected, info)
+	}
+}
+
+// TestSorterPivots tests sorting with the different pivot strategies.
+func TestSorterPivots(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	pivots := map[string]sort.PivotStrategy{
+		"ninther":         sort.NintherPivot,
+		"median of three": sort.MedianOfThreePivot,
+		"middle":          sort.MiddlePivot,
+		"random":          sort.RandomPivot,
+	}
+	for name, pivot := range pivots {
+		s := sort.NewSorter(sort.WithPivot(pivot), sort.WithThresholds(sort.Thresholds{
+			Sequential: 4,
+			Parallel:   1024,
+		}))
+		for _, size := range []int{0, 1, 2, 3, 10, 3000} {
+			is := generateIntSlice(size)
+			expected := make([]int, size)
+			copy(expected, is)
+			stdsort.Ints(expected)
+
+			s.Sort(is)
+			assert.Equal([]int(is), expected, fmt.Sprintf("%s pivot with size %d", name, size))
+		}
+	}
+}
+
+// TestPivotStrategies tests that the strategies return an
+// index inside of the range.
+func TestPivotStrategies(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	pivots := []sort.PivotStrategy{
+		sort.NintherPivot,
+		sort.MedianOfThreePivot,
+		sort.MiddlePivot,
+		sort.RandomPivot,
+	}
+	for _, pivot := range pivots {
+		for i := 0; i < 100; i++ {
+			is := generateIntSlice(100)
+			p := pivot(is, 10, 60)
+			assert.True(p >= 10 && p <= 60, "pivot inside of range")
+		}
+	}
+	is := stdsort.IntSlice{5, 1, 9}
+	assert.Equal(sort.MedianOfThreePivot(is, 0, 2), 1)
+	assert.Equal(is[1], 5)
+}
+
+// TestCalibrate tests the calibration of the thresholds.
+func TestCalibrate(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	th := sort.Calibrate(1 << 11)
+	assert.Logf("calibrated thresholds: %+v", th)
+	assert.True(th.Sequential >= 4 && th.Sequential <= 64, "sequential threshold is a candidate")
+	assert.True(th.Parallel >= 1<<10 && th.Parallel <= 1<<11, "parallel threshold is below sample size")
+
+	is := generateIntSlice(5000)
+	sort.NewSorter(sort.WithThresholds(th)).Sort(is)
+	assert.True(stdsort.IsSorted(is), "sorted with calibrated thresholds")
+}
+
+// EOF
@@ -0,0 +1,171 @@
+// Tideland Go Data Structures and Algorithms - Sort - Stable
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"sort"
+	"sync"
+)
+
+//--------------------
+// HELPING FUNCS
+//--------------------
+
+// symMerge merges the sorted ranges data[a:m] and data[m:b] stable and
+// in place using the SymMerge algorithm by Pok-Son Kim and Arne Kutzner.
+// The two independent merges at its end run concurrently for ranges
+// larger than the parallel threshold.
+func symMerge(data sort.Interface, a, m, b, parallel int) {
+	if m-a == 1 {
+		// Insert data[a] into data[m:b] by binary search.
+		i, j := m, b
+		for i < j {
+			h := int(uint(i+j) >> 1)
+			if data.Less(h, a) {
+				i = h + 1
+			} else {
+				j = h
+			}
+		}
+		for k := a; k < i-1; k++ {
+			data.Swap(k, k+1)
+		}
+		return
+	}
+	if b-m == 1 {
+		// Insert data[m] into data[a:m] by binary search.
+		i, j := a, m
+		for i < j {
+			h := int(uint(i+j) >> 1)
+			if !data.Less(m, h) {
+				i = h + 1
+			} else {
+				j = h
+			}
+		}
+		for k := m; k > i; k-- {
+			data.Swap(k, k-1)
+		}
+		return
+	}
+	mid := int(uint(a+b) >> 1)
+	n := mid + m
+	var start, r int
+	if m > mid {
+		start = n - b
+		r = mid
+	} else {
+		start = a
+		r = m
+	}
+	p := n - 1
+	for start < r {
+		c := int(uint(start+r) >> 1)
+		if !data.Less(p-c, c) {
+			start = c + 1
+		} else {
+			r = c
+		}
+	}
+	end := n - start
+	if start < m && m < end {
+		rotate(data, start, m, end)
+	}
+	merge := func(a, m, b int) {
+		if a < m && m < b {
+			symMerge(data, a, m, b, parallel)
+		}
+	}
+	if b-a > parallel {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			merge(a, start, mid)
+		}()
+		merge(mid, end, b)
+		wg.Wait()
+	} else {
+		merge(a, start, mid)
+		merge(mid, end, b)
+	}
+}
+
+// rotate exchanges the ranges data[a:m] and data[m:b].
+func rotate(data sort.Interface, a, m, b int) {
+	i := m - a
+	j := b - m
+	for i != j {
+		if i > j {
+			swapRange(data, m-i, m, j)
+			i -= j
+		} else {
+			swapRange(data, m-i, m+j-i, i)
+			j -= i
+		}
+	}
+	swapRange(data, m-i, m, i)
+}
+
+// swapRange swaps the n elements starting at a with those starting at b.
+func swapRange(data sort.Interface, a, b, n int) {
+	for i := 0; i < n; i++ {
+		data.Swap(a+i, b+i)
+	}
+}
+
+// sequentialMergeSort using itself recursively.
+func (s *Sorter) sequentialMergeSort(data sort.Interface, lo, hi int) {
+	if hi-lo > s.opts.thresholds.Sequential {
+		// Use sequential merge sort.
+		mid := int(uint(lo+hi) >> 1)
+		s.sequentialMergeSort(data, lo, mid)
+		s.sequentialMergeSort(data, mid+1, hi)
+		symMerge(data, lo, mid+1, hi+1, s.opts.thresholds.Parallel)
+	} else {
+		// Use insertion sort.
+		insertionSort(data, lo, hi)
+	}
+}
+
+// parallelMergeSort using itself recursively and concurrent.
+func (s *Sorter) parallelMergeSort(data sort.Interface, lo, hi int, done chan bool) {
+	if hi-lo > s.opts.thresholds.Parallel {
+		// Parallel merge sort.
+		mid := int(uint(lo+hi) >> 1)
+		partDone := make(chan bool)
+		go s.parallelMergeSort(data, lo, mid, partDone)
+		go s.parallelMergeSort(data, mid+1, hi, partDone)
+		// Wait for the end of both sorts before merging.
+		<-partDone
+		<-partDone
+		symMerge(data, lo, mid+1, hi+1, s.opts.thresholds.Parallel)
+	} else {
+		// Sequential merge sort.
+		s.sequentialMergeSort(data, lo, hi)
+	}
+	// Signal that it's done.
+	done <- true
+}
+
+//--------------------
+// PARALLEL MERGE SORT
+//--------------------
+
+// Stable sorts the data according to the standard sort interface
+// while keeping the original order of equal elements. Internally it
+// uses a parallel merge sort merging in place.
+func Stable(data sort.Interface) {
+	NewSorter().Stable(data)
+}
+
+// EOF
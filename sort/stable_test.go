@@ -0,0 +1,119 @@
+// Tideland Go Data Structures and Algorithms - Sort - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math/rand"
+	stdsort "sort"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/sort"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestStable tests the stable sort against the one of the
+// standard library with sizes around the thresholds.
+func TestStable(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	dt := sort.DefaultThresholds()
+	seq, par := dt.Sequential, dt.Parallel
+	sizes := []int{0, 1, 2, seq, seq + 1, seq + 2, 3*seq + 5, par, par + 1, par + 2, 3*par + 17}
+	for _, size := range sizes {
+		for _, keys := range []int{1, 10, size + 1} {
+			rs := generateRecords(size, keys)
+			expected := make(Records, size)
+			copy(expected, rs)
+			stdsort.Stable(expected)
+
+			sort.Stable(rs)
+			assert.Equal(rs, expected, fmt.Sprintf("size %d with %d keys", size, keys))
+		}
+	}
+}
+
+// TestStableMultiKey tests sorting by multiple keys, the
+// secondary one first.
+func TestStableMultiKey(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	ps := make([]Person, 50000)
+	for i := range ps {
+		ps[i] = Person{Name: string(rune('a' + rand.Intn(26))), Age: rand.Intn(100)}
+	}
+
+	sort.Stable(ByName(ps))
+	sort.Stable(ByAge(ps))
+	assert.True(stdsort.SliceIsSorted(ps, func(i, j int) bool {
+		if ps[i].Age != ps[j].Age {
+			return ps[i].Age < ps[j].Age
+		}
+		return ps[i].Name < ps[j].Name
+	}), "sorted by age and name")
+}
+
+// Benchmark the standard stable sort.
+func BenchmarkStandardStable(b *testing.B) {
+	is := generateIntSlice(b.N)
+	stdsort.Stable(is)
+}
+
+// Benchmark the parallel stable sort.
+func BenchmarkStable(b *testing.B) {
+	is := generateIntSlice(b.N)
+	sort.Stable(is)
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// Record has a key for sorting and its original position.
+type Record struct {
+	Key int
+	Pos int
+}
+
+// Records sorts records by key.
+type Records []Record
+
+func (rs Records) Len() int           { return len(rs) }
+func (rs Records) Less(i, j int) bool { return rs[i].Key < rs[j].Key }
+func (rs Records) Swap(i, j int)      { rs[i], rs[j] = rs[j], rs[i] }
+
+// generateRecords generates records with the given number of keys.
+func generateRecords(count, keys int) Records {
+	rs := make(Records, count)
+	for i := range rs {
+		rs[i] = Record{rand.Intn(keys), i}
+	}
+	return rs
+}
+
+// ByName sorts persons by name.
+type ByName []Person
+
+func (ps ByName) Len() int           { return len(ps) }
+func (ps ByName) Less(i, j int) bool { return ps[i].Name < ps[j].Name }
+func (ps ByName) Swap(i, j int)      { ps[i], ps[j] = ps[j], ps[i] }
+
+// ByAge sorts persons by age.
+type ByAge []Person
+
+func (ps ByAge) Len() int           { return len(ps) }
+func (ps ByAge) Less(i, j int) bool { return ps[i].Age < ps[j].Age }
+func (ps ByAge) Swap(i, j int)      { ps[i], ps[j] = ps[j], ps[i] }
+
+// EOF
@@ -0,0 +1,204 @@
+// Tideland Go Data Structures and Algorithms - Sort - Verification
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort // import "tideland.dev/go/dsa/sort"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"tideland.dev/go/trace/failure"
+)
+
+//--------------------
+// PATTERNS
+//--------------------
+
+// Pattern generates data of a given size with a layout known
+// to be problematic for some sorting algorithms.
+type Pattern struct {
+	Name     string
+	Generate func(size int) []int
+}
+
+// Patterns returns the patterns used for verifying and benchmarking
+// sorting functions.
+func Patterns() []Pattern {
+	return []Pattern{
+		{"random", func(size int) []int {
+			return generate(size, func(i int) int { return rand.Int() })
+		}},
+		{"sorted", func(size int) []int {
+			return generate(size, func(i int) int { return i })
+		}},
+		{"reversed", func(size int) []int {
+			return generate(size, func(i int) int { return size - i })
+		}},
+		{"all equal", func(size int) []int {
+			return generate(size, func(i int) int { return 42 })
+		}},
+		{"organ pipe", func(size int) []int {
+			return generate(size, func(i int) int {
+				if i < size/2 {
+					return i
+				}
+				return size - i
+			})
+		}},
+		{"sawtooth", func(size int) []int {
+			tooth := size/10 + 1
+			return generate(size, func(i int) int { return i % tooth })
+		}},
+		{"nearly sorted", func(size int) []int {
+			data := generate(size, func(i int) int { return i })
+			for i := 0; i < size/100+1 && size > 1; i++ {
+				a, b := rand.Intn(size), rand.Intn(size)
+				data[a], data[b] = data[b], data[a]
+			}
+			return data
+		}},
+		{"median-of-3 killer", medianOfThreeKiller},
+		{"few unique", func(size int) []int {
+			return generate(size, func(i int) int { return rand.Intn(4) })
+		}},
+		{"many duplicates", func(size int) []int {
+			unique := int(math.Sqrt(float64(size))) + 1
+			return generate(size, func(i int) int { return rand.Intn(unique) })
+		}},
+	}
+}
+
+//--------------------
+// VERIFICATION
+//--------------------
+
+// Verify sorts the data of all patterns in all sizes with the passed
+// function. It checks if the results are sorted and permutations of the
+// input. The first failure is returned as error. Without sizes the
+// ones around the default thresholds are used.
+func Verify(sortf func(data sort.Interface), sizes ...int) error {
+	if len(sizes) == 0 {
+		sizes = defaultSizes()
+	}
+	for _, pattern := range Patterns() {
+		for _, size := range sizes {
+			data := pattern.Generate(size)
+			expected := make([]int, size)
+			copy(expected, data)
+			sort.Ints(expected)
+
+			sortf(sort.IntSlice(data))
+
+			if !sort.IntsAreSorted(data) {
+				return failure.New("pattern %q with size %d is not sorted", pattern.Name, size)
+			}
+			for i := range data {
+				if data[i] != expected[i] {
+					return failure.New("pattern %q with size %d is no permutation of the input", pattern.Name, size)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//--------------------
+// BENCHMARKING
+//--------------------
+
+// BenchmarkResult contains the durations for sorting the data of one
+// pattern with the benchmarked and the standard sort function. The
+// speedup is the standard duration divided by the benchmarked one.
+type BenchmarkResult struct {
+	Pattern  string
+	Size     int
+	Duration time.Duration
+	Standard time.Duration
+	Speedup  float64
+}
+
+// Benchmark measures the sorting of the data of all patterns in the
+// given size with the passed function and with sort.Sort() of the
+// standard library. Each measurement is the fastest of three runs.
+func Benchmark(sortf func(data sort.Interface), size int) []BenchmarkResult {
+	var results []BenchmarkResult
+	for _, pattern := range Patterns() {
+		data := pattern.Generate(size)
+		result := BenchmarkResult{
+			Pattern:  pattern.Name,
+			Size:     size,
+			Duration: measure(data, sortf),
+			Standard: measure(data, sort.Sort),
+		}
+		if result.Duration > 0 {
+			result.Speedup = float64(result.Standard) / float64(result.Duration)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// defaultSizes returns sizes around the default thresholds.
+func defaultSizes() []int {
+	seq, par := sequentialThreshold, parallelThreshold
+	return []int{0, 1, 2, 3, seq, seq + 1, seq + 2, 100, par, par + 1, par + 2, 4*par + 7}
+}
+
+// generate creates data of the given size with the function.
+func generate(size int, f func(i int) int) []int {
+	data := make([]int, size)
+	for i := range data {
+		data[i] = f(i)
+	}
+	return data
+}
+
+// medianOfThreeKiller creates data leading to a quadratic runtime
+// of quicksort choosing the median of the first, the middle, and
+// the last element as pivot. It's the sequence by David Musser.
+func medianOfThreeKiller(size int) []int {
+	data := make([]int, size)
+	k := size / 2
+	for i := 1; i <= k; i++ {
+		if i%2 == 1 {
+			data[i-1] = i
+			data[i] = k + i
+		}
+		data[k+i-1] = 2 * i
+	}
+	if size%2 == 1 {
+		data[size-1] = size
+	}
+	return data
+}
+
+// measure returns the fastest of three sortings of copies of the data.
+func measure(data []int, sortf func(data sort.Interface)) time.Duration {
+	work := make([]int, len(data))
+	fastest := time.Duration(0)
+	for i := 0; i < 3; i++ {
+		copy(work, data)
+		start := time.Now()
+		sortf(sort.IntSlice(work))
+		if d := time.Since(start); i == 0 || d < fastest {
+			fastest = d
+		}
+	}
+	return fastest
+}
+
+// EOF
@@ -0,0 +1,136 @@
+// Tideland Go Data Structures and Algorithms - Sort - Unit Tests
+//
+// Copyright (C) 2019 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package sort_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	stdsort "sort"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/sort"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestVerify verifies the sorting functions of the package with
+// small sizes. The sorters use small thresholds, so all their
+// algorithms are covered.
+func TestVerify(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	sortfs := sortFuncs(func(err error) {
+		assert.Nil(err)
+	}, sort.WithThresholds(sort.Thresholds{
+		Sequential: 7,
+		Parallel:   63,
+	}))
+	for name, sortf := range sortfs {
+		assert.Nil(sort.Verify(sortf, 0, 1, 2, 3, 8, 64, 65, 500), name)
+	}
+}
+
+// TestVerifyFailures tests the detection of wrong sortings.
+func TestVerifyFailures(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	err := sort.Verify(func(data stdsort.Interface) {}, 100)
+	assert.ErrorMatch(err, `.*pattern "random" with size 100 is not sorted.*`)
+
+	err = sort.Verify(func(data stdsort.Interface) {
+		is := data.(stdsort.IntSlice)
+		for i := range is {
+			is[i] = i
+		}
+	}, 100)
+	assert.ErrorMatch(err, `.*pattern "random" with size 100 is no permutation of the input.*`)
+}
+
+// TestBenchmark tests the comparison with the standard library.
+func TestBenchmark(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	results := sort.Benchmark(sort.Sort, 10000)
+	assert.Length(results, len(sort.Patterns()))
+	for _, result := range results {
+		assert.Logf("%-20s %10v %10v %6.2fx", result.Pattern, result.Duration, result.Standard, result.Speedup)
+		assert.Equal(result.Size, 10000)
+		assert.True(result.Duration > 0 && result.Standard > 0, "durations are measured")
+	}
+}
+
+// Benchmark the verification of the sorting functions with
+// the default sizes around the default thresholds.
+func BenchmarkVerify(b *testing.B) {
+	sortfs := sortFuncs(func(err error) {
+		if err != nil {
+			b.Fatal(err)
+		}
+	})
+	for name, sortf := range sortfs {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := sort.Verify(sortf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Benchmark the sorting of all patterns.
+func BenchmarkPatterns(b *testing.B) {
+	for _, pattern := range sort.Patterns() {
+		b.Run(pattern.Name, func(b *testing.B) {
+			is := stdsort.IntSlice(pattern.Generate(b.N))
+			b.ResetTimer()
+			sort.Sort(is)
+		})
+	}
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// sortFuncs returns the sorting functions of the package to verify. The
+// sorters are created with the options, errors are passed to check.
+func sortFuncs(check func(err error), options ...sort.Option) map[string]func(data stdsort.Interface) {
+	sorter := sort.NewSorter(options...)
+	contextOptions := append([]sort.Option{sort.WithWorkers(4)}, options...)
+	sortfs := map[string]func(data stdsort.Interface){
+		"sort":   sorter.Sort,
+		"stable": sorter.Stable,
+		"context": func(data stdsort.Interface) {
+			check(sort.SortContext(context.Background(), data, contextOptions...))
+		},
+		"top-k": func(data stdsort.Interface) {
+			sorter.TopK(data, data.Len())
+		},
+		"radix": func(data stdsort.Interface) {
+			sort.RadixInts([]int(data.(stdsort.IntSlice)))
+		},
+	}
+	pivots := map[string]sort.PivotStrategy{
+		"ninther":         sort.NintherPivot,
+		"median of three": sort.MedianOfThreePivot,
+		"middle":          sort.MiddlePivot,
+		"random":          sort.RandomPivot,
+	}
+	for name, pivot := range pivots {
+		sortfs["sorter with "+name+" pivot"] = sort.NewSorter(append([]sort.Option{sort.WithPivot(pivot)}, options...)...).Sort
+	}
+	return sortfs
+}
+
+// EOF
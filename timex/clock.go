@@ -0,0 +1,365 @@
+// Tideland Go Data Structures and Algorithms - Time Extensions - Clock
+//
+// Copyright (C) 2009-2020 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package timex
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//--------------------
+// CLOCK
+//--------------------
+
+// Clock provides the current time and waiting for durations. It
+// allows to replace the real time in tests, see FakeClock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Sleep pauses for at least the duration.
+	Sleep(d time.Duration)
+
+	// After returns a channel receiving the current time
+	// after the duration.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer creates a timer firing once after the duration.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker creates a ticker firing in intervals of the duration.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer sends the current time on its channel when it fires.
+type Timer interface {
+	// C returns the channel receiving the time.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing. It returns false
+	// if the timer already fired or has been stopped.
+	Stop() bool
+
+	// Reset changes the timer to fire after the duration. It returns
+	// true if the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// Ticker sends the current time on its channel in intervals.
+// Ticks are dropped if they are not received in time.
+type Ticker interface {
+	// C returns the channel receiving the ticks.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker.
+	Stop()
+
+	// Reset stops the ticker and resets its interval
+	// to the duration.
+	Reset(d time.Duration)
+}
+
+//--------------------
+// REAL CLOCK
+//--------------------
+
+// NewRealClock returns the clock using the functions
+// of the time package.
+func NewRealClock() Clock {
+	return realClock{}
+}
+
+// realClock implements Clock with the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// realTimer implements Timer with a time.Timer.
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (rt realTimer) C() <-chan time.Time {
+	return rt.timer.C
+}
+
+func (rt realTimer) Stop() bool {
+	return rt.timer.Stop()
+}
+
+func (rt realTimer) Reset(d time.Duration) bool {
+	return rt.timer.Reset(d)
+}
+
+// realTicker implements Ticker with a time.Ticker.
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (rt realTicker) C() <-chan time.Time {
+	return rt.ticker.C
+}
+
+func (rt realTicker) Stop() {
+	rt.ticker.Stop()
+}
+
+func (rt realTicker) Reset(d time.Duration) {
+	rt.ticker.Reset(d)
+}
+
+//--------------------
+// FAKE CLOCK
+//--------------------
+
+// FakeClock is a Clock for tests. Its time only changes when it is
+// advanced or set. Then the timers and tickers whose times have come
+// fire in order. So functions waiting for durations can be tested
+// without delays and with deterministic results.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFakeClock creates a fake clock starting at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	fc := &FakeClock{
+		now: now,
+	}
+	fc.cond = sync.NewCond(&fc.mu)
+	return fc
+}
+
+// Now implements Clock.
+func (fc *FakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+// Sleep implements Clock. It blocks until the clock
+// has been advanced by the duration.
+func (fc *FakeClock) Sleep(d time.Duration) {
+	<-fc.After(d)
+}
+
+// After implements Clock.
+func (fc *FakeClock) After(d time.Duration) <-chan time.Time {
+	return fc.NewTimer(d).C()
+}
+
+// NewTimer implements Clock.
+func (fc *FakeClock) NewTimer(d time.Duration) Timer {
+	fw := &fakeWaiter{
+		clock: fc,
+		c:     make(chan time.Time, 1),
+	}
+	fw.reset(d, 0)
+	return fakeTimer{fw}
+}
+
+// NewTicker implements Clock. Like time.NewTicker() it
+// panics if the duration is not positive.
+func (fc *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	fw := &fakeWaiter{
+		clock: fc,
+		c:     make(chan time.Time, 1),
+	}
+	fw.reset(d, d)
+	return fakeTicker{fw}
+}
+
+// Advance moves the time of the clock forward by the duration
+// and fires the timers and tickers whose times have come.
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.advanceTo(fc.now.Add(d))
+}
+
+// Set moves the time of the clock to the given one. Timers and
+// tickers fire if it is after the current time.
+func (fc *FakeClock) Set(t time.Time) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if t.After(fc.now) {
+		fc.advanceTo(t)
+		return
+	}
+	fc.now = t
+}
+
+// Waiters returns the number of active timers and tickers.
+func (fc *FakeClock) Waiters() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return len(fc.waiters)
+}
+
+// BlockUntil waits until at least n timers and tickers are active. It
+// helps to advance the clock only after a tested goroutine started
+// waiting.
+func (fc *FakeClock) BlockUntil(n int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for len(fc.waiters) < n {
+		fc.cond.Wait()
+	}
+}
+
+// advanceTo fires the waiters in the order of their deadlines until
+// the time. Tickers are rescheduled, timers removed.
+func (fc *FakeClock) advanceTo(t time.Time) {
+	for len(fc.waiters) > 0 && !fc.waiters[0].deadline.After(t) {
+		fw := fc.waiters[0]
+		fc.now = fw.deadline
+		fw.fire(fc.now)
+		if fw.period > 0 {
+			fw.deadline = fw.deadline.Add(fw.period)
+		} else {
+			fc.remove(fw)
+		}
+		fc.sortWaiters()
+	}
+	fc.now = t
+}
+
+// add registers the waiter.
+func (fc *FakeClock) add(fw *fakeWaiter) {
+	fc.waiters = append(fc.waiters, fw)
+	fc.sortWaiters()
+	fc.cond.Broadcast()
+}
+
+// remove unregisters the waiter. It returns false if it
+// was not registered.
+func (fc *FakeClock) remove(fw *fakeWaiter) bool {
+	for i, w := range fc.waiters {
+		if w == fw {
+			fc.waiters = append(fc.waiters[:i], fc.waiters[i+1:]...)
+			fc.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// sortWaiters orders the waiters by their deadlines.
+func (fc *FakeClock) sortWaiters() {
+	sort.SliceStable(fc.waiters, func(i, j int) bool {
+		return fc.waiters[i].deadline.Before(fc.waiters[j].deadline)
+	})
+}
+
+// fakeWaiter is a timer or, with a period, a ticker of a fake clock.
+type fakeWaiter struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+	period   time.Duration
+}
+
+// fire sends the time without blocking.
+func (fw *fakeWaiter) fire(t time.Time) {
+	select {
+	case fw.c <- t:
+	default:
+	}
+}
+
+// reset unregisters the waiter and registers it again with the new
+// duration and period. Timers with non-positive durations fire at once.
+// It returns true if the waiter had been registered.
+func (fw *fakeWaiter) reset(d, period time.Duration) bool {
+	fc := fw.clock
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	active := fc.remove(fw)
+	fw.deadline = fc.now.Add(d)
+	fw.period = period
+	if d <= 0 && period == 0 {
+		fw.fire(fc.now)
+		return active
+	}
+	fc.add(fw)
+	return active
+}
+
+// stop unregisters the waiter. It returns true if it had been registered.
+func (fw *fakeWaiter) stop() bool {
+	fw.clock.mu.Lock()
+	defer fw.clock.mu.Unlock()
+	return fw.clock.remove(fw)
+}
+
+// fakeTimer implements Timer for the fake clock.
+type fakeTimer struct {
+	fw *fakeWaiter
+}
+
+func (ft fakeTimer) C() <-chan time.Time {
+	return ft.fw.c
+}
+
+func (ft fakeTimer) Stop() bool {
+	return ft.fw.stop()
+}
+
+func (ft fakeTimer) Reset(d time.Duration) bool {
+	return ft.fw.reset(d, 0)
+}
+
+// fakeTicker implements Ticker for the fake clock.
+type fakeTicker struct {
+	fw *fakeWaiter
+}
+
+func (ft fakeTicker) C() <-chan time.Time {
+	return ft.fw.c
+}
+
+func (ft fakeTicker) Stop() {
+	ft.fw.stop()
+}
+
+func (ft fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	ft.fw.reset(d, d)
+}
+
+// EOF
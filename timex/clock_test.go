@@ -0,0 +1,149 @@
+// Tideland Go Data Structures and Algorithms - Time Extensions - Clock - Unit Tests
+//
+// Copyright (C) 2009-2020 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package timex_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+	"time"
+
+	"tideland.dev/go/audit/asserts"
+	"tideland.dev/go/dsa/timex"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestRealClock tests the clock using the real time.
+func TestRealClock(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	clock := timex.NewRealClock()
+	start := clock.Now()
+	clock.Sleep(5 * time.Millisecond)
+	<-clock.After(5 * time.Millisecond)
+	assert.True(clock.Now().Sub(start) >= 10*time.Millisecond)
+
+	timer := clock.NewTimer(time.Hour)
+	assert.True(timer.Reset(time.Millisecond))
+	<-timer.C()
+	assert.False(timer.Stop())
+
+	ticker := clock.NewTicker(time.Millisecond)
+	<-ticker.C()
+	<-ticker.C()
+	ticker.Stop()
+}
+
+// TestFakeClockTimers tests timers of the fake clock.
+func TestFakeClockTimers(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	start := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	clock := timex.NewFakeClock(start)
+	assert.Equal(clock.Now(), start)
+
+	a := clock.NewTimer(10 * time.Second)
+	b := clock.NewTimer(5 * time.Second)
+	c := clock.After(20 * time.Second)
+	assert.Equal(clock.Waiters(), 3)
+
+	clock.Advance(4 * time.Second)
+	assert.False(fired(a.C(), b.C(), c))
+
+	clock.Advance(time.Second)
+	assert.Equal(<-b.C(), start.Add(5*time.Second))
+	assert.False(fired(a.C(), c))
+	assert.False(b.Stop())
+
+	assert.True(a.Reset(time.Minute))
+	clock.Advance(time.Minute)
+	assert.Equal(<-c, start.Add(20*time.Second))
+	assert.Equal(<-a.C(), start.Add(65*time.Second))
+	assert.Equal(clock.Now(), start.Add(65*time.Second))
+	assert.Equal(clock.Waiters(), 0)
+
+	d := clock.NewTimer(time.Second)
+	assert.True(d.Stop())
+	clock.Advance(time.Minute)
+	assert.False(fired(d.C()))
+
+	e := clock.NewTimer(0)
+	assert.Equal(<-e.C(), clock.Now())
+
+	clock.Set(start)
+	assert.Equal(clock.Now(), start)
+}
+
+// TestFakeClockTicker tests tickers of the fake clock.
+func TestFakeClockTicker(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	start := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	clock := timex.NewFakeClock(start)
+	ticker := clock.NewTicker(time.Second)
+
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Second)
+		assert.Equal(<-ticker.C(), start.Add(time.Duration(i)*time.Second))
+	}
+
+	// Ticks not received in time are dropped.
+	clock.Advance(10 * time.Second)
+	assert.Equal(<-ticker.C(), start.Add(4*time.Second))
+	assert.False(fired(ticker.C()))
+
+	ticker.Reset(time.Minute)
+	clock.Advance(time.Minute)
+	assert.Equal(<-ticker.C(), start.Add(13*time.Second+time.Minute))
+
+	ticker.Stop()
+	clock.Advance(time.Hour)
+	assert.False(fired(ticker.C()))
+}
+
+// TestFakeClockSleep tests sleeping with the fake clock.
+func TestFakeClockSleep(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	start := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	clock := timex.NewFakeClock(start)
+	woken := make(chan time.Time)
+
+	go func() {
+		clock.Sleep(time.Hour)
+		woken <- clock.Now()
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(30 * time.Minute)
+	clock.Advance(30 * time.Minute)
+	assert.Equal(<-woken, start.Add(time.Hour))
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// fired checks if one of the channels received a time.
+func fired(cs ...<-chan time.Time) bool {
+	for _, c := range cs {
+		select {
+		case <-c:
+			return true
+		default:
+		}
+	}
+	return false
+}
+
+// EOF
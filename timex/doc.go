@@ -6,6 +6,17 @@
 // by the new BSD license.
 
 // Package timex adds some useful functions for the work with them time type.
+//
+// Retry() and RetryContext() execute a function until it succeeds. The
+// RetryStrategy defines the number of attempts, the breaks between them
+// with linear or exponential backoff and optional jitter, and the maximum
+// elapsed time. Errors of the function stop the retries unless they are
+// classified as retryable, the final error is a RetryError containing the
+// number of attempts and the elapsed time.
+//
+// A Clock provides the time and waiting for durations. Next to the real one
+// the FakeClock only changes when it is advanced explicitly. Setting it in a
+// RetryStrategy allows testing breaks deterministically and without delays.
 package timex
 
 // EOF
@@ -12,23 +12,66 @@ package timex
 //--------------------
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
 	"time"
 
 	"tideland.dev/go/trace/failure"
 )
 
 //--------------------
-// RETRY
+// RETRY STRATEGY
 //--------------------
 
+// Jitter defines how the breaks between retries are randomized, so
+// that multiple retrying clients don't synchronize.
+type Jitter int
+
+// Jitter variants.
+const (
+	// NoJitter uses the calculated breaks as they are.
+	NoJitter Jitter = iota
+
+	// FullJitter uses a random break between zero and the
+	// calculated one.
+	FullJitter
+
+	// EqualJitter uses half of the calculated break plus a
+	// random duration up to the other half.
+	EqualJitter
+
+	// DecorrelatedJitter uses a random break between the initial
+	// one and three times the previous one, limited by MaxBreak.
+	DecorrelatedJitter
+)
+
 // RetryStrategy describes how often the function in Retry is executed, the
 // initial break between those retries, how much this time is incremented
-// for each retry, and the maximum timeout.
+// for each retry, and the maximum timeout. Additionally the break can be
+// multiplied for an exponential backoff, be limited by a maximum, and be
+// randomized by a jitter. The timeout is the maximum total elapsed time,
+// a zero timeout means no limit.
+//
+// Errors returned by the function stop the retries unless Classify reports
+// them as retryable. OnRetry is called after each failed attempt with its
+// number, its error, and the break before the next one.
+//
+// The Clock is used for measuring the time and waiting. Without one the
+// real time is used, a FakeClock allows testing the breaks.
 type RetryStrategy struct {
 	Count          int
 	Break          time.Duration
 	BreakIncrement time.Duration
+	Multiplier     float64
+	MaxBreak       time.Duration
+	Jitter         Jitter
 	Timeout        time.Duration
+	Classify       func(err error) bool
+	OnRetry        func(attempt int, err error, next time.Duration)
+	Clock          Clock
 }
 
 // ShortAttempt returns a predefined short retry strategy.
@@ -61,27 +104,257 @@ func LongAttempt() RetryStrategy {
 	}
 }
 
+// ExponentialBackoff returns a retry strategy doubling the initial break
+// with each retry up to the maximum break. The breaks are randomized with
+// full jitter and the retries stop after the maximum elapsed time.
+func ExponentialBackoff(initial, maxBreak, maxElapsed time.Duration) RetryStrategy {
+	return RetryStrategy{
+		Count:      math.MaxInt,
+		Break:      initial,
+		Multiplier: 2,
+		MaxBreak:   maxBreak,
+		Jitter:     FullJitter,
+		Timeout:    maxElapsed,
+	}
+}
+
+// Breaks returns the first n breaks of the strategy. In case of a jitter
+// they differ between calls. So it helps to check a configuration.
+func (rs RetryStrategy) Breaks(n int) []time.Duration {
+	b := newBackoff(rs)
+	breaks := make([]time.Duration, n)
+	for i := range breaks {
+		breaks[i] = b.next()
+	}
+	return breaks
+}
+
+//--------------------
+// ERRORS
+//--------------------
+
+// RetryError is returned by Retry when it stops without success. It
+// contains the number of attempts, the elapsed time, and the last error
+// returned by the function, which is nil if it only returned false. The
+// reason for stopping, like the cancellation of the context, can also be
+// checked with errors.Is() and errors.As().
+type RetryError struct {
+	Attempts int
+	Elapsed  time.Duration
+	Err      error
+	reason   error
+}
+
+// Error implements the error interface.
+func (re *RetryError) Error() string {
+	var msg string
+	switch {
+	case re.reason == nil:
+		msg = re.Err.Error()
+	case re.Err == nil:
+		msg = re.reason.Error()
+	default:
+		msg = re.reason.Error() + ": " + re.Err.Error()
+	}
+	return fmt.Sprintf("%s (%d attempts in %v)", msg, re.Attempts, re.Elapsed)
+}
+
+// Unwrap returns the reason for stopping and the last error
+// of the function.
+func (re *RetryError) Unwrap() []error {
+	var errs []error
+	for _, err := range []error{re.reason, re.Err} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Permanent marks the error as not retryable, whatever the
+// classification of the retry strategy says.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// RetryableErrors returns a classification reporting errors matching
+// one of the targets with errors.Is() as retryable.
+func RetryableErrors(targets ...error) func(err error) bool {
+	return func(err error) bool {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//--------------------
+// RETRY
+//--------------------
+
 // Retry executes the passed function until it returns true or an error.
 // These retries are restricted by the retry strategy. It's a simple
 // approach, more flexible ways can be found at together/wait.
 func Retry(f func() (bool, error), rs RetryStrategy) error {
-	timeout := time.Now().Add(rs.Timeout)
-	sleep := rs.Break
-	for i := 0; i < rs.Count; i++ {
+	return RetryContext(context.Background(), f, rs)
+}
+
+// RetryContext works like Retry but also stops waiting when the context
+// is cancelled. Retries whose break would exceed the timeout are not
+// started. Errors are returned as RetryError.
+func RetryContext(ctx context.Context, f func() (bool, error), rs RetryStrategy) error {
+	clock := rs.Clock
+	if clock == nil {
+		clock = NewRealClock()
+	}
+	start := clock.Now()
+	b := newBackoff(rs)
+	var lastErr error
+	stop := func(attempts int, reason error) error {
+		return &RetryError{
+			Attempts: attempts,
+			Elapsed:  clock.Now().Sub(start),
+			Err:      lastErr,
+			reason:   reason,
+		}
+	}
+	for attempt := 1; attempt <= rs.Count; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return stop(attempt-1, err)
+		}
 		done, err := f()
 		if err != nil {
-			return err
-		}
-		if done {
+			lastErr = err
+			if !rs.retryable(err) {
+				return stop(attempt, nil)
+			}
+		} else if done {
 			return nil
 		}
-		if time.Now().After(timeout) {
-			return failure.New("retried longer than %v", rs.Timeout)
+		if attempt == rs.Count {
+			break
+		}
+		sleep := b.next()
+		if rs.Timeout > 0 && clock.Now().Sub(start)+sleep > rs.Timeout {
+			return stop(attempt, failure.New("retried longer than %v", rs.Timeout))
 		}
-		time.Sleep(sleep)
-		sleep += rs.BreakIncrement
+		if rs.OnRetry != nil {
+			rs.OnRetry(attempt, err, sleep)
+		}
+		if err := wait(ctx, clock, sleep); err != nil {
+			return stop(attempt, err)
+		}
+	}
+	return stop(rs.Count, failure.New("retried more than %d times", rs.Count))
+}
+
+//--------------------
+// PRIVATE
+//--------------------
+
+// permanentError marks an error as not retryable.
+type permanentError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (pe *permanentError) Error() string {
+	return pe.err.Error()
+}
+
+// Unwrap returns the marked error.
+func (pe *permanentError) Unwrap() error {
+	return pe.err
+}
+
+// retryable checks if the error allows further retries.
+func (rs RetryStrategy) retryable(err error) bool {
+	var pe *permanentError
+	if rs.Classify == nil || errors.As(err, &pe) {
+		return false
+	}
+	return rs.Classify(err)
+}
+
+// backoff calculates the breaks of a retry strategy.
+type backoff struct {
+	rs       RetryStrategy
+	current  time.Duration
+	previous time.Duration
+}
+
+// newBackoff creates the backoff for the retry strategy.
+func newBackoff(rs RetryStrategy) *backoff {
+	return &backoff{
+		rs:       rs,
+		current:  rs.Break,
+		previous: rs.Break,
+	}
+}
+
+// next returns the next break and calculates the following one.
+func (b *backoff) next() time.Duration {
+	sleep := b.limit(b.current)
+	switch b.rs.Jitter {
+	case FullJitter:
+		sleep = randomBetween(0, sleep)
+	case EqualJitter:
+		sleep = sleep/2 + randomBetween(0, sleep-sleep/2)
+	case DecorrelatedJitter:
+		sleep = b.limit(randomBetween(b.rs.Break, 3*b.previous))
+		b.previous = sleep
+	}
+	if b.rs.Multiplier > 0 {
+		if current := float64(b.current) * b.rs.Multiplier; current < math.MaxInt64 {
+			b.current = time.Duration(current)
+		} else {
+			b.current = math.MaxInt64
+		}
+	}
+	b.current = b.limit(b.current + b.rs.BreakIncrement)
+	return sleep
+}
+
+// limit restricts the duration to the maximum break, if set. Negative
+// durations caused by overflows are set to the longest possible one.
+func (b *backoff) limit(d time.Duration) time.Duration {
+	if d < 0 {
+		d = math.MaxInt64
+	}
+	if b.rs.MaxBreak > 0 && d > b.rs.MaxBreak {
+		return b.rs.MaxBreak
+	}
+	return d
+}
+
+// randomBetween returns a random duration between lo and hi.
+func randomBetween(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	n := int64(hi - lo)
+	if n < math.MaxInt64 {
+		n++
+	}
+	return lo + time.Duration(rand.Int63n(n))
+}
+
+// wait sleeps for the duration of the clock or until
+// the context is cancelled.
+func wait(ctx context.Context, clock Clock, d time.Duration) error {
+	timer := clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
 	}
-	return failure.New("retried more than %d times", rs.Count)
 }
 
 // EOF
@@ -12,7 +12,9 @@ package timex_test
 //--------------------
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -137,4 +139,324 @@ func TestRetryTooOften(t *testing.T) {
 	assert.ErrorMatch(err, ".* retried more than .* times")
 }
 
+// TestRetryContextCancel tests the cancellation of a retry.
+func TestRetryContextCancel(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	err := timex.RetryContext(ctx, func() (bool, error) {
+		count++
+		if count == 3 {
+			cancel()
+		}
+		return false, nil
+	}, timex.LongAttempt())
+	assert.True(errors.Is(err, context.Canceled))
+	assert.Equal(count, 3)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = timex.RetryContext(ctx, func() (bool, error) {
+		return false, nil
+	}, timex.RetryStrategy{Count: 10, Break: time.Minute})
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+	assert.True(time.Since(start) < time.Minute)
+}
+
+// TestRetryBreaks tests the breaks of the backoff strategies.
+func TestRetryBreaks(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	rs := timex.RetryStrategy{
+		Break:          10 * time.Millisecond,
+		BreakIncrement: 5 * time.Millisecond,
+	}
+	assert.Equal(rs.Breaks(4), []time.Duration{
+		10 * time.Millisecond, 15 * time.Millisecond,
+		20 * time.Millisecond, 25 * time.Millisecond,
+	})
+
+	rs = timex.RetryStrategy{
+		Break:      10 * time.Millisecond,
+		Multiplier: 2,
+		MaxBreak:   50 * time.Millisecond,
+	}
+	assert.Equal(rs.Breaks(5), []time.Duration{
+		10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond,
+		50 * time.Millisecond, 50 * time.Millisecond,
+	})
+
+	rs = timex.RetryStrategy{
+		Break:      time.Millisecond,
+		Multiplier: 10,
+	}
+	for _, b := range rs.Breaks(100) {
+		assert.True(b > 0)
+	}
+}
+
+// TestRetryJitter tests the randomization of the breaks.
+func TestRetryJitter(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	rs := timex.ExponentialBackoff(10*time.Millisecond, time.Second, time.Minute)
+	for i, b := range rs.Breaks(20) {
+		ceiling := min(10*time.Millisecond<<i, time.Second)
+		assert.True(b >= 0 && b <= ceiling)
+	}
+
+	rs.Jitter = timex.EqualJitter
+	for i, b := range rs.Breaks(20) {
+		ceiling := min(10*time.Millisecond<<i, time.Second)
+		assert.True(b >= ceiling/2 && b <= ceiling)
+	}
+
+	rs.Jitter = timex.DecorrelatedJitter
+	previous := rs.Break
+	for _, b := range rs.Breaks(20) {
+		assert.True(b >= rs.Break && b <= min(3*previous, rs.MaxBreak))
+		previous = b
+	}
+
+	// Jittered breaks of a strategy are not all the same.
+	rs.Jitter = timex.FullJitter
+	a, b := rs.Breaks(10), rs.Breaks(10)
+	assert.Different(a, b)
+}
+
+// TestRetryMaxElapsed tests that no retry is started whose
+// break exceeds the maximum elapsed time.
+func TestRetryMaxElapsed(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	clock := timex.NewFakeClock(time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC))
+	rs := timex.RetryStrategy{
+		Count:      10,
+		Break:      10 * time.Millisecond,
+		Multiplier: 2,
+		Timeout:    100 * time.Millisecond,
+		Clock:      clock,
+	}
+	count := 0
+	errc := make(chan error)
+
+	go func() {
+		errc <- timex.Retry(func() (bool, error) {
+			count++
+			return false, nil
+		}, rs)
+	}()
+
+	// Breaks of 10, 20, and 40 milliseconds fit into the timeout,
+	// the next one of 80 milliseconds doesn't.
+	for _, d := range rs.Breaks(3) {
+		clock.BlockUntil(1)
+		clock.Advance(d)
+	}
+	err := <-errc
+	assert.ErrorMatch(err, ".* retried longer than .*")
+	assert.Equal(count, 4)
+	var re *timex.RetryError
+	assert.True(errors.As(err, &re))
+	assert.Equal(re.Elapsed, 70*time.Millisecond)
+}
+
+// TestRetryClassify tests the classification of errors.
+func TestRetryClassify(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	errTemporary := errors.New("temporary")
+	errFatal := errors.New("fatal")
+	rs := timex.RetryStrategy{
+		Count:    10,
+		Break:    time.Millisecond,
+		Classify: timex.RetryableErrors(errTemporary),
+	}
+
+	count := 0
+	err := timex.Retry(func() (bool, error) {
+		count++
+		if count < 3 {
+			return false, fmt.Errorf("wrapped: %w", errTemporary)
+		}
+		return true, nil
+	}, rs)
+	assert.Nil(err)
+	assert.Equal(count, 3)
+
+	count = 0
+	err = timex.Retry(func() (bool, error) {
+		count++
+		if count < 3 {
+			return false, errTemporary
+		}
+		return false, errFatal
+	}, rs)
+	assert.True(errors.Is(err, errFatal))
+	assert.Equal(count, 3)
+
+	count = 0
+	err = timex.Retry(func() (bool, error) {
+		count++
+		return false, timex.Permanent(errTemporary)
+	}, rs)
+	assert.True(errors.Is(err, errTemporary))
+	assert.Equal(count, 1)
+
+	count = 0
+	err = timex.Retry(func() (bool, error) {
+		count++
+		return false, errTemporary
+	}, rs)
+	assert.ErrorMatch(err, ".* retried more than 10 times: temporary .*")
+	assert.True(errors.Is(err, errTemporary))
+	assert.Equal(count, 10)
+}
+
+// TestRetryOnRetry tests the hook called after failed attempts.
+func TestRetryOnRetry(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	errTemporary := errors.New("temporary")
+	var attempts []int
+	var errs []error
+	var breaks []time.Duration
+	rs := timex.RetryStrategy{
+		Count:          5,
+		Break:          time.Millisecond,
+		BreakIncrement: time.Millisecond,
+		Classify:       timex.RetryableErrors(errTemporary),
+		OnRetry: func(attempt int, err error, next time.Duration) {
+			attempts = append(attempts, attempt)
+			errs = append(errs, err)
+			breaks = append(breaks, next)
+		},
+	}
+	count := 0
+	err := timex.Retry(func() (bool, error) {
+		count++
+		if count%2 == 1 {
+			return false, errTemporary
+		}
+		return count == 4, nil
+	}, rs)
+	assert.Nil(err)
+	assert.Equal(attempts, []int{1, 2, 3})
+	assert.Equal(errs, []error{errTemporary, nil, errTemporary})
+	assert.Equal(breaks, []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond})
+}
+
+// TestRetryError tests the error returned after failed retries.
+func TestRetryError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	errTemporary := errors.New("temporary")
+	rs := timex.RetryStrategy{
+		Count:    3,
+		Break:    5 * time.Millisecond,
+		Classify: timex.RetryableErrors(errTemporary),
+	}
+	err := timex.Retry(func() (bool, error) {
+		return false, errTemporary
+	}, rs)
+	var re *timex.RetryError
+	assert.True(errors.As(err, &re))
+	assert.Equal(re.Attempts, 3)
+	assert.Equal(re.Err, errTemporary)
+	assert.True(re.Elapsed >= 10*time.Millisecond)
+
+	err = timex.Retry(func() (bool, error) {
+		return false, nil
+	}, rs)
+	assert.True(errors.As(err, &re))
+	assert.Equal(re.Attempts, 3)
+	assert.Nil(re.Err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = timex.RetryContext(ctx, func() (bool, error) {
+		return true, nil
+	}, rs)
+	assert.True(errors.Is(err, context.Canceled))
+	assert.True(errors.As(err, &re))
+	assert.Equal(re.Attempts, 0)
+}
+
+// TestRetryLastAttempt tests that no retry is announced or
+// waited for after the last attempt.
+func TestRetryLastAttempt(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	retries := 0
+	onRetry := func(attempt int, err error, next time.Duration) {
+		retries++
+	}
+	count := 0
+	err := timex.Retry(func() (bool, error) {
+		count++
+		return false, nil
+	}, timex.RetryStrategy{
+		Count:   5,
+		Break:   time.Millisecond,
+		OnRetry: onRetry,
+	})
+	assert.ErrorMatch(err, ".* retried more than 5 times .*")
+	assert.Equal(count, 5)
+	assert.Equal(retries, 4)
+
+	// With only one attempt there's no retry and no break.
+	retries = 0
+	start := time.Now()
+	err = timex.Retry(func() (bool, error) {
+		return false, nil
+	}, timex.RetryStrategy{
+		Count:   1,
+		Break:   time.Hour,
+		Timeout: 2 * time.Hour,
+		OnRetry: onRetry,
+	})
+	assert.ErrorMatch(err, ".* retried more than 1 times .*")
+	assert.Equal(retries, 0)
+	assert.True(time.Since(start) < time.Minute)
+}
+
+// TestRetryFakeClock tests the breaks of a retry with a fake clock.
+func TestRetryFakeClock(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	clock := timex.NewFakeClock(time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC))
+	rs := timex.RetryStrategy{
+		Count:      5,
+		Break:      time.Second,
+		Multiplier: 3,
+		MaxBreak:   time.Minute,
+		Timeout:    time.Hour,
+		Clock:      clock,
+	}
+	var breaks []time.Duration
+	rs.OnRetry = func(attempt int, err error, next time.Duration) {
+		breaks = append(breaks, next)
+	}
+	errc := make(chan error)
+
+	go func() {
+		errc <- timex.Retry(func() (bool, error) {
+			return false, nil
+		}, rs)
+	}()
+
+	for _, d := range rs.Breaks(4) {
+		clock.BlockUntil(1)
+		clock.Advance(d)
+	}
+	err := <-errc
+	assert.ErrorMatch(err, ".* retried more than 5 times .*")
+	assert.Equal(breaks, []time.Duration{time.Second, 3 * time.Second, 9 * time.Second, 27 * time.Second})
+	var re *timex.RetryError
+	assert.True(errors.As(err, &re))
+	assert.Equal(re.Elapsed, 40*time.Second)
+}
+
 // EOF